@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func (bc *BotController) accountsHandler(m *tb.Message) {
+	sc := h.MakeSubcommandHandler("/"+CMD_ACCOUNTS, true)
+	sc.
+		Add("list", bc.accountsHandleList).
+		Add("add", bc.accountsHandleAdd).
+		Add("close", bc.accountsHandleClose).
+		Add("seed", bc.accountsHandleSeed)
+	_, err := sc.Handle(m)
+	if err != nil {
+		bc.accountsHelp(m, nil)
+	}
+}
+
+func (bc *BotController) accountsHelp(m *tb.Message, err error) {
+	errorMsg := ""
+	if err != nil {
+		errorMsg += fmt.Sprintf("Error executing your command: %s\n\n", err.Error())
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), errorMsg+`Usage help for /accounts:
+/accounts list
+/accounts add <account> [<account>...]
+/accounts close <account>
+/accounts seed
+
+Your registry of open accounts is used to check the accounts you enter in transactions. As long as it is empty, no checks are performed. Accounts not in the registry need to be confirmed by sending them again.
+
+'seed' adds all accounts from your /suggestions to the registry.`)
+}
+
+func (bc *BotController) accountsHandleList(m *tb.Message, params ...string) {
+	if len(params) > 0 {
+		bc.accountsHelp(m, fmt.Errorf("unexpected parameters"))
+		return
+	}
+	accounts, err := bc.Repo.GetAccounts(m)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "Error encountered while retrieving your accounts: "+err.Error())
+		return
+	}
+	if len(accounts) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), "Your registry of open accounts is currently empty. You can add accounts using '/accounts add <account>' or '/accounts seed'.")
+		return
+	}
+	messageSplits := bc.MergeMessagesHonorSendLimit(append([]string{"These accounts are currently open:\n"}, accounts...), "\n")
+	for _, message := range messageSplits {
+		bc.Bot.SendSilent(bc, Recipient(m), message)
+	}
+}
+
+func (bc *BotController) accountsHandleAdd(m *tb.Message, params ...string) {
+	if len(params) == 0 {
+		bc.accountsHelp(m, fmt.Errorf("no account to add provided"))
+		return
+	}
+	accounts := strings.Fields(strings.Join(params, " "))
+	for _, account := range accounts {
+		if err := h.IsValidAccount(account); err != nil {
+			bc.Bot.SendSilent(bc, Recipient(m), "Adding accounts failed: "+err.Error())
+			return
+		}
+	}
+	for _, account := range accounts {
+		err := bc.Repo.AddAccount(m, account)
+		if err != nil {
+			bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Error encountered while adding account (%s): %s", account, err.Error()))
+			return
+		}
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), "Successfully added account(s).")
+}
+
+func (bc *BotController) accountsHandleClose(m *tb.Message, params ...string) {
+	if len(params) != 1 {
+		bc.accountsHelp(m, fmt.Errorf("please provide exactly one account to close"))
+		return
+	}
+	err := bc.Repo.CloseAccount(m, params[0])
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "Error encountered while closing account: "+err.Error())
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully closed account '%s'.", params[0]))
+}
+
+func (bc *BotController) accountsHandleSeed(m *tb.Message, params ...string) {
+	if len(params) > 0 {
+		bc.accountsHelp(m, fmt.Errorf("unexpected parameters"))
+		return
+	}
+	cachedAccounts, err := bc.Repo.GetCachedAccounts(m)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "Error encountered while retrieving your account suggestions: "+err.Error())
+		return
+	}
+	added := []string{}
+	invalid := []string{}
+	for _, account := range cachedAccounts {
+		if h.IsValidAccount(account) != nil {
+			invalid = append(invalid, account)
+			continue
+		}
+		isAdded, err := bc.Repo.SeedAccount(m, account)
+		if err != nil {
+			bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Error encountered while adding account (%s): %s", account, err.Error()))
+			return
+		}
+		if isAdded {
+			added = append(added, account)
+		}
+	}
+	message := fmt.Sprintf("Added %d account(s) from your suggestions to your registry.", len(added))
+	if len(added) > 0 {
+		message += "\n\n" + strings.Join(added, "\n")
+	}
+	if len(invalid) > 0 {
+		message += "\n\nThe following suggestions are no valid account names and have been left out:\n\n" + strings.Join(invalid, "\n")
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), message)
+}
+
+// confirmAccountInput checks account inputs against the user's registry of open accounts.
+// Accounts not in the registry need to be sent twice in a row to be accepted.
+func (bc *BotController) confirmAccountInput(m *tb.Message, tx Tx) bool {
+	field := tx.NextField()
+	if field == nil || field.FieldName != h.FIELD_ACCOUNT {
+		return true
+	}
+	account := strings.TrimSpace(m.Text)
+	if (field.IsOptional && account == SKIP_OPTIONAL) || h.IsValidAccount(account) != nil {
+		// Invalid account names are rejected by the input handler
+		return true
+	}
+	accounts, err := bc.Repo.GetAccounts(m)
+	if err != nil {
+		bc.Logf(ERROR, m, "Could not check account against registry: %s", err.Error())
+		return true
+	}
+	if len(accounts) == 0 || h.ArrayContains(accounts, account) {
+		return true
+	}
+	if bc.State.ConfirmAccount(m, account) {
+		bc.Logf(DEBUG, m, "Account '%s' not in registry has been confirmed", account)
+		return true
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("The account '%s' is not open in your registry of accounts (/%s). "+
+		"To use it anyway, please send it again. Otherwise please enter another account.", account, CMD_ACCOUNTS))
+	return false
+}
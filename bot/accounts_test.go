@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestAccountsAddListSeed(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	bc.commandAccounts(&MockContext{M: &tb.Message{Chat: chat, Text: "/accounts add Assets:Wallet expenses:food"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Adding accounts failed", "invalid account should be rejected")
+
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(chat.ID, "Assets:Wallet").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(chat.ID, "Expenses:Food").WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandAccounts(&MockContext{M: &tb.Message{Chat: chat, Text: "/accounts add Assets:Wallet Expenses:Food"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully added account(s)", "")
+
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}).AddRow("Assets:Wallet").AddRow("Expenses:Food"))
+	bc.commandAccounts(&MockContext{M: &tb.Message{Chat: chat, Text: "/accounts list"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Assets:Wallet\nExpenses:Food", "")
+
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("account:from", "Assets:Wallet").
+			AddRow("account:to", "Expenses:Rent").
+			AddRow("account:to", "someAccount").
+			AddRow("description:", "Rent"))
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(chat.ID, "Assets:Wallet").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(chat.ID, "Expenses:Rent").WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandAccounts(&MockContext{M: &tb.Message{Chat: chat, Text: "/accounts seed"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Added 1 account(s)", "")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Expenses:Rent", "")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "left out:\n\nsomeAccount", "")
+
+	mock.ExpectExec(`UPDATE "bot::account"`).WithArgs(chat.ID, "Expenses:Gym").WillReturnResult(sqlmock.NewResult(1, 0))
+	bc.commandAccounts(&MockContext{M: &tb.Message{Chat: chat, Text: "/accounts close Expenses:Gym"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "is not open in your registry", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAccountsConfirmUnknownAccount(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandCreateSimpleTx(&MockContext{M: &tb.Message{Chat: chat}})
+	tx := bc.State.txStates[12345]
+	tx.Input(&tb.Message{Text: "17.34"})     // amount
+	tx.Input(&tb.Message{Text: "Groceries"}) // description
+
+	// invalid account names are rejected by the field handler
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "wallet"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.AllLastSentWhat), "at least two components", "")
+
+	registry := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"account"}).AddRow("Assets:Wallet").AddRow("Expenses:Food")
+	}
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).WillReturnRows(registry())
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Assets:Cash"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.AllLastSentWhat), "please send it again", "unknown account should need confirmation")
+	helpers.TestExpect(t, strings.Contains(tx.Debug(), "Assets:Cash"), false, "account should not have been accepted yet")
+
+	// sending it again confirms the account
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).WillReturnRows(registry())
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Assets:Cash"}})
+	helpers.TestStringContains(t, tx.Debug(), "account:from:Assets:Cash", "confirmed account should be accepted")
+
+	// accounts in the registry are accepted directly
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).WillReturnRows(registry())
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Expenses:Food"}})
+	helpers.TestStringContains(t, tx.Debug(), "account:to:Expenses:Food", "registered account should be accepted directly")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TAG).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectExec(`INSERT INTO "bot::transaction"`).WithArgs(chat.ID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: POSTING_DONE}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.AllLastSentWhat), "Successfully recorded your transaction", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// BalanceTx records a balance assertion, e.g. '2026-10-17 balance Assets:Cash  123.45 EUR'
+type BalanceTx struct {
+	*SimpleTx
+}
+
+func CreateBalanceTx(suggestedCur string) (*BalanceTx, error) {
+	tx, err := CreateSimpleTx(suggestedCur, TEMPLATE_BALANCE)
+	if err != nil {
+		return nil, err
+	}
+	simpleTx := tx.(*SimpleTx)
+	// Ask for the account first, then for the amount it holds
+	sort.SliceStable(simpleTx.nextFields, func(i, j int) bool {
+		return simpleTx.nextFields[i].FieldName == c.FIELD_ACCOUNT && simpleTx.nextFields[j].FieldName != c.FIELD_ACCOUNT
+	})
+	return &BalanceTx{SimpleTx: simpleTx}, nil
+}
+
+func (tx *BalanceTx) NextHint(r *crud.Repo, m *tb.Message) *Hint {
+	hint := tx.SimpleTx.NextHint(r, m)
+	if hint == nil || tx.NextField().FieldName != c.FIELD_ACCOUNT {
+		return hint
+	}
+	// Besides accounts used for balance assertions before, suggest the open accounts (or all accounts used so far)
+	accounts, err := r.GetAccounts(m)
+	if err != nil {
+		crud.LogDbf(r, ERROR, m, "Error occurred getting open accounts: %s", err.Error())
+	}
+	if len(accounts) == 0 {
+		accounts, err = r.GetCachedAccounts(m)
+		if err != nil {
+			crud.LogDbf(r, ERROR, m, "Error occurred getting cached accounts: %s", err.Error())
+		}
+	}
+	for _, account := range accounts {
+		if c.IsValidAccount(account) == nil && !c.ArrayContains(hint.KeyboardOptions, account) {
+			hint.KeyboardOptions = append(hint.KeyboardOptions, account)
+		}
+	}
+	return hint
+}
+
+func (tx *BalanceTx) Debug() string {
+	return fmt.Sprintf("BalanceTx{remainingFields=%v, data=%v}", len(tx.nextFields), tx.data)
+}
@@ -0,0 +1,40 @@
+package bot_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestBalanceAssertion(t *testing.T) {
+	tx, err := bot.CreateBalanceTx("EUR")
+	if err != nil {
+		t.Fatalf("Creating balance assertion should work: %s", err.Error())
+	}
+	tx.SetDate("2022-04-11", 0)
+	helpers.TestExpect(t, tx.NextField().FieldName, helpers.FIELD_ACCOUNT, "account should be asked for first")
+
+	_, err = tx.Input(&tb.Message{Text: "checking"})
+	if err == nil {
+		t.Errorf("Invalid account name should be rejected")
+	}
+	tx.Input(&tb.Message{Text: "Assets:Cash"})
+
+	_, err = tx.Input(&tb.Message{Text: "12.50 USD @ 0.92 EUR"})
+	if err == nil {
+		t.Errorf("Balance assertions should not accept price annotations")
+	}
+	isDone, err := tx.Input(&tb.Message{Text: "-12.5"})
+	if err != nil {
+		t.Fatalf("Entering negative balance should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, isDone, true, "")
+
+	template, err := tx.FillTemplate("EUR", "vacation", 0)
+	if err != nil {
+		t.Fatalf("Filling balance assertion should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, template, "2022-04-11 balance Assets:Cash                -12.50 EUR\n", "balance should keep its sign, not be indented and carry no tag")
+}
@@ -0,0 +1,257 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+// Simple arithmetic expressions for amount inputs, e.g. '12.5+3*2' or '(45.90-5)/3'.
+// In templates, expressions are evaluated over the entered amount, e.g. 'amount*19%' or 'amount-1.50'.
+// Grammar (usual operator precedence, left-associative):
+//   expression = term { ("+" | "-") term }
+//   term       = factor { ("*" | "/") factor }
+//   factor     = ("+" | "-") factor | value [ "%" ]
+//   value      = number | variable | "(" expression ")"
+
+type tokenType int
+
+const (
+	TOKEN_NUMBER tokenType = iota
+	TOKEN_OPERATOR
+	TOKEN_OPEN
+	TOKEN_CLOSE
+	TOKEN_VARIABLE
+	TOKEN_INVALID
+)
+
+type token struct {
+	t        tokenType
+	value    string
+	position int // 1-based position of the token within the input
+}
+
+type expressionParser struct {
+	input     string
+	locale    *c.Locale
+	variables map[string]c.Decimal // only available in template expressions
+	tokens    []*token
+	idx       int
+}
+
+func EvaluateExpression(input string) (c.Decimal, error) {
+	return evaluateExpression(input, nil)
+}
+
+// evaluateExpression evaluates the expression with numbers given in the locale. Without locale, the separators are guessed.
+func evaluateExpression(input string, locale *c.Locale) (c.Decimal, error) {
+	p := &expressionParser{input: input, locale: locale}
+	return p.evaluate()
+}
+
+// evaluateAmountExpression evaluates a template expression over the entered amount, e.g. 'amount*0.19'.
+// The exact result is rounded (half away from zero) to the precision of the currency, or of the amount if it has more decimal places.
+func evaluateAmountExpression(expression string, amount c.Decimal, precision int) (c.Decimal, error) {
+	p := &expressionParser{input: expression, variables: map[string]c.Decimal{c.FIELD_AMOUNT: amount}}
+	result, err := p.evaluate()
+	if err != nil {
+		return c.Decimal{}, fmt.Errorf("invalid expression '%s': %s", expression, err.Error())
+	}
+	if places, _ := amount.Places(); places > precision {
+		precision = places
+	}
+	return result.Round(precision), nil
+}
+
+// ValidateAmountExpression checks a template expression, e.g. 'amount*19%', before the template is saved
+func ValidateAmountExpression(expression string) error {
+	_, err := evaluateAmountExpression(expression, c.NewDecimal(1), 0)
+	return err
+}
+
+func (p *expressionParser) evaluate() (c.Decimal, error) {
+	p.tokenize()
+	if len(p.tokens) == 0 {
+		return c.Decimal{}, fmt.Errorf("parsing failed: no value given")
+	}
+	result, err := p.parseExpression()
+	if err != nil {
+		return c.Decimal{}, err
+	}
+	if t := p.peek(); t != nil {
+		return c.Decimal{}, p.errorAt(t, "unexpected value")
+	}
+	return result, nil
+}
+
+func isOperator(r rune) bool {
+	return strings.ContainsRune("+-*/%()", r)
+}
+
+// parseNumber converts a number entered by the user into its canonical form
+func parseNumber(value string, locale *c.Locale) (string, error) {
+	if locale == nil {
+		return handleThousandsSeparators(value)
+	}
+	return locale.Normalize(value)
+}
+
+func (p *expressionParser) tokenize() {
+	runes := []rune(p.input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '(':
+			p.tokens = append(p.tokens, &token{t: TOKEN_OPEN, value: string(r), position: i + 1})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, &token{t: TOKEN_CLOSE, value: string(r), position: i + 1})
+			i++
+		case isOperator(r):
+			p.tokens = append(p.tokens, &token{t: TOKEN_OPERATOR, value: string(r), position: i + 1})
+			i++
+		default:
+			start := i
+			t := TOKEN_NUMBER
+			if unicode.IsLetter(r) {
+				t = TOKEN_VARIABLE
+			}
+			for i < len(runes) && !isOperator(runes[i]) {
+				if (t == TOKEN_NUMBER && !p.locale.IsNumberRune(runes[i])) || (t == TOKEN_VARIABLE && !unicode.IsLetter(runes[i])) {
+					t = TOKEN_INVALID
+				}
+				i++
+			}
+			p.tokens = append(p.tokens, &token{t: t, value: string(runes[start:i]), position: start + 1})
+		}
+	}
+}
+
+func (p *expressionParser) peek() *token {
+	if p.idx >= len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.idx]
+}
+
+func (p *expressionParser) next() *token {
+	t := p.peek()
+	if t != nil {
+		p.idx++
+	}
+	return t
+}
+
+func (p *expressionParser) errorAt(t *token, reason string) error {
+	if t == nil {
+		return fmt.Errorf("parsing failed at end of expression '%s' (position %d): %s", p.input, len([]rune(p.input))+1, reason)
+	}
+	return fmt.Errorf("parsing failed at value '%s' (position %d): %s", t.value, t.position, reason)
+}
+
+func (p *expressionParser) parseExpression() (c.Decimal, error) {
+	result, err := p.parseTerm()
+	if err != nil {
+		return c.Decimal{}, err
+	}
+	for t := p.peek(); t != nil && t.t == TOKEN_OPERATOR && (t.value == "+" || t.value == "-"); t = p.peek() {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return c.Decimal{}, err
+		}
+		if t.value == "+" {
+			result = result.Add(right)
+		} else {
+			result = result.Sub(right)
+		}
+	}
+	return result, nil
+}
+
+func (p *expressionParser) parseTerm() (c.Decimal, error) {
+	result, err := p.parseFactor()
+	if err != nil {
+		return c.Decimal{}, err
+	}
+	for t := p.peek(); t != nil && t.t == TOKEN_OPERATOR && (t.value == "*" || t.value == "/"); t = p.peek() {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return c.Decimal{}, err
+		}
+		if t.value == "*" {
+			result = result.Mul(right)
+		} else {
+			if right.IsZero() {
+				return c.Decimal{}, p.errorAt(t, "division by zero")
+			}
+			result = result.Quo(right)
+		}
+	}
+	return result, nil
+}
+
+func (p *expressionParser) parseFactor() (c.Decimal, error) {
+	if t := p.peek(); t != nil && t.t == TOKEN_OPERATOR && (t.value == "+" || t.value == "-") {
+		p.next()
+		value, err := p.parseFactor()
+		if err != nil {
+			return c.Decimal{}, err
+		}
+		if t.value == "-" {
+			value = value.Neg()
+		}
+		return value, nil
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return c.Decimal{}, err
+	}
+	if t := p.peek(); t != nil && t.t == TOKEN_OPERATOR && t.value == "%" {
+		p.next()
+		value = value.Quo(c.NewDecimal(100))
+	}
+	return value, nil
+}
+
+func (p *expressionParser) parseValue() (c.Decimal, error) {
+	t := p.next()
+	if t == nil {
+		return c.Decimal{}, p.errorAt(nil, "expected a number or '('")
+	}
+	switch t.t {
+	case TOKEN_OPERATOR:
+		return c.Decimal{}, p.errorAt(t, "expected a number or '('")
+	case TOKEN_VARIABLE:
+		if value, exists := p.variables[t.value]; exists {
+			return value, nil
+		}
+		if p.variables != nil {
+			return c.Decimal{}, p.errorAt(t, fmt.Sprintf("unknown variable. Expressions can only refer to the '%s'", c.FIELD_AMOUNT))
+		}
+	case TOKEN_OPEN:
+		value, err := p.parseExpression()
+		if err != nil {
+			return c.Decimal{}, err
+		}
+		closing := p.next()
+		if closing == nil || closing.t != TOKEN_CLOSE {
+			return c.Decimal{}, p.errorAt(closing, "expected closing ')'")
+		}
+		return value, nil
+	case TOKEN_NUMBER:
+		value, err := parseNumber(t.value, p.locale)
+		if err != nil {
+			return c.Decimal{}, err
+		}
+		v, err := c.ParseDecimal(value)
+		if err != nil {
+			return c.Decimal{}, p.errorAt(t, "not a valid number")
+		}
+		return v, nil
+	}
+	return c.Decimal{}, p.errorAt(t, "not a number")
+}
@@ -0,0 +1,68 @@
+package bot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func expressionCase(t *testing.T, expression string, expected string) {
+	result, err := bot.EvaluateExpression(expression)
+	helpers.TestExpect(t, err, nil, "Should not throw an error for "+expression)
+	helpers.TestExpect(t, result.String(), expected, expression)
+}
+
+func expressionErrorCase(t *testing.T, expression, expectedError string) {
+	_, err := bot.EvaluateExpression(expression)
+	if err == nil || !strings.Contains(err.Error(), expectedError) {
+		t.Errorf("%s: Expected error containing '%s', got: %v", expression, expectedError, err)
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	expressionCase(t, "42", "42")
+	expressionCase(t, "1+2*3", "7")
+	expressionCase(t, "(1+2)*3", "9")
+	expressionCase(t, "10-2-3", "5")
+	expressionCase(t, "12/3/2", "2")
+	expressionCase(t, "-3+5", "2")
+	expressionCase(t, "2*-3", "-6")
+	expressionCase(t, "((2))", "2")
+	expressionCase(t, "1,000.50+1", "1001.5")
+	expressionCase(t, "0.1+0.2", "0.3")
+	expressionCase(t, "(45.90-5)/2", "20.45")
+	expressionCase(t, "80*15%", "12")
+	expressionCase(t, "(10+10)%*50", "10")
+}
+
+func TestEvaluateExpressionErrors(t *testing.T) {
+	expressionErrorCase(t, "", "no value given")
+	expressionErrorCase(t, "1+", "at end of expression '1+' (position 3)")
+	expressionErrorCase(t, "(1+2", "expected closing ')'")
+	expressionErrorCase(t, "1+2)", "failed at value ')' (position 4): unexpected value")
+	expressionErrorCase(t, "2*/3", "failed at value '/' (position 3)")
+	expressionErrorCase(t, "4/0", "division by zero")
+	expressionErrorCase(t, "4/abc", "failed at value 'abc' (position 3): not a number")
+	expressionErrorCase(t, "24,24.7*2", "invalid separators in value '24,24.7'")
+}
+
+func TestValidateAmountExpression(t *testing.T) {
+	for _, valid := range []string{"amount*0.19", "amount*19%", "amount-1.50", "-amount/1.19", "(amount+2)*3"} {
+		if err := bot.ValidateAmountExpression(valid); err != nil {
+			t.Errorf("Expression '%s' should be valid: %s", valid, err.Error())
+		}
+	}
+	for invalid, expectedError := range map[string]string{
+		"amount*":      "at end of expression",
+		"amount*price": "failed at value 'price' (position 8): unknown variable",
+		"amount%%":     "failed at value '%' (position 8)",
+		"amount/0":     "division by zero",
+	} {
+		err := bot.ValidateAmountExpression(invalid)
+		if err == nil || !strings.Contains(err.Error(), expectedError) {
+			t.Errorf("%s: Expected error containing '%s', got: %v", invalid, expectedError, err)
+		}
+	}
+}
@@ -19,6 +19,7 @@ func (bc *BotController) configHandler(m *tb.Message) {
 		Add("notify", bc.configHandleNotification).
 		Add("about", bc.configHandleAbout).
 		Add("tz_offset", bc.configHandleTimezoneOffset).
+		Add("locale", bc.configHandleLocale).
 		Add("delete_account", bc.configHandleAccountDelete).
 		Add("omit_slash", bc.configHandleOmitLeadingSlash)
 	_, err := sc.Handle(m)
@@ -36,7 +37,10 @@ func (bc *BotController) configHelp(m *tb.Message, err error) {
 	tz, _ := time.Now().Zone()
 	filledTemplate, err := helpers.Template(`Usage help for /{{.CONFIG_COMMAND}}:
 
+/{{.CONFIG_COMMAND}} currency - Get default currency and currencies allowed for amounts
 /{{.CONFIG_COMMAND}} currency <c> - Change default currency
+/{{.CONFIG_COMMAND}} currency allow <c> [<c>...] - Only accept these currencies (and the default currency) for amounts
+/{{.CONFIG_COMMAND}} currency allow off - Accept any currency for amounts
 
 Tags will be added to each new transaction with a '#':
 
@@ -55,6 +59,11 @@ Timezone offset from {{.TZ}} to honor for notifications and current date (if set
 /{{.CONFIG_COMMAND}} tz_offset - Get current timezone offset from {{.TZ}} (default 0)
 /{{.CONFIG_COMMAND}} tz_offset <hours> - Set timezone offset from {{.TZ}}
 
+Number format of amounts you enter and amounts shown to you (amounts in transactions always use '.' as decimal separator):
+
+/{{.CONFIG_COMMAND}} locale - Get currently set locale
+/{{.CONFIG_COMMAND}} locale <locale> - Set locale, one of: {{.LOCALES}}
+
 Feature toggle: Also activate commands without leading slash if not in transaction
 
 /{{.CONFIG_COMMAND}} omit_slash - Get current setting value
@@ -70,6 +79,7 @@ Reset your data stored by the bot. WARNING: This action is permanent!
 `, map[string]interface{}{
 		"CONFIG_COMMAND": CMD_CONFIG,
 		"TZ":             tz,
+		"LOCALES":        localesHelp(),
 	})
 	if err != nil {
 		bc.Logf(ERROR, m, "Parsing configHelp template failed: %s", err.Error())
@@ -82,7 +92,14 @@ func (bc *BotController) configHandleCurrency(m *tb.Message, params ...string) {
 	currency := bc.Repo.UserGetCurrency(m)
 	if len(params) == 0 { // 0 params: GET currency
 		// Return currently set currency
-		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your current currency is set to '%s'. To change it add the new currency to use to the command like this: '/%s currency EUR'.", currency, CMD_CONFIG))
+		allowedMsg := "Amounts are accepted in any currency."
+		if commodities := bc.Repo.UserGetCommodities(m); len(commodities) > 0 {
+			allowedMsg = fmt.Sprintf("Besides it, amounts are only accepted in these currencies: %s.", strings.Join(commodities, ", "))
+		}
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your current currency is set to '%s'. To change it add the new currency to use to the command like this: '/%s currency EUR'.\n\n%s", currency, CMD_CONFIG, allowedMsg))
+		return
+	} else if params[0] == "allow" {
+		bc.configHandleCurrencyAllow(m, params[1:]...)
 		return
 	} else if len(params) > 1 { // 2 or more params: too many
 		bc.configHelp(m, fmt.Errorf("invalid amount of parameters specified"))
@@ -90,6 +107,10 @@ func (bc *BotController) configHandleCurrency(m *tb.Message, params ...string) {
 	}
 	// Set new currency
 	newCurrency := params[0]
+	if err := helpers.IsValidCommodity(newCurrency); err != nil {
+		bc.configHelp(m, err)
+		return
+	}
 	err := bc.Repo.UserSetCurrency(m, newCurrency)
 	if err != nil {
 		bc.Bot.SendSilent(bc, Recipient(m), "An error ocurred saving your currency preference: "+err.Error())
@@ -98,6 +119,35 @@ func (bc *BotController) configHandleCurrency(m *tb.Message, params ...string) {
 	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Changed default currency for all future transactions from '%s' to '%s'.", currency, newCurrency))
 }
 
+func (bc *BotController) configHandleCurrencyAllow(m *tb.Message, params ...string) {
+	if len(params) == 0 {
+		bc.configHelp(m, fmt.Errorf("please provide the currencies to allow or 'off'"))
+		return
+	}
+	commodities := []string{}
+	if len(params) > 1 || params[0] != "off" {
+		for _, commodity := range params {
+			if err := helpers.IsValidCommodity(commodity); err != nil {
+				bc.configHelp(m, err)
+				return
+			}
+			if !helpers.ArrayContains(commodities, commodity) {
+				commodities = append(commodities, commodity)
+			}
+		}
+	}
+	err := bc.Repo.UserSetCommodities(m, commodities)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "An error ocurred saving your allowed currencies: "+err.Error())
+		return
+	}
+	if len(commodities) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), "From now on amounts are accepted in any currency.")
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("From now on amounts are only accepted in your default currency and %s.", strings.Join(commodities, ", ")))
+}
+
 func (bc *BotController) configHandleTag(m *tb.Message, params ...string) {
 	if len(params) == 0 {
 		// GET tag
@@ -288,6 +338,37 @@ func (bc *BotController) configHandleOmitLeadingSlash(m *tb.Message, params ...s
 	}
 }
 
+func (bc *BotController) configHandleLocale(m *tb.Message, params ...string) {
+	locale := bc.Repo.UserGetLocale(m)
+	if len(params) == 0 { // 0 params: GET
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your current locale is set to '%s'. To change it add the new locale to the command like this: '/%s locale de'.", locale.String(), CMD_CONFIG))
+		return
+	} else if len(params) > 1 { // 2 or more params: too many
+		bc.configHelp(m, fmt.Errorf("invalid amount of parameters specified"))
+		return
+	}
+	// Set new locale
+	newLocale, err := helpers.GetLocale(params[0])
+	if err != nil {
+		bc.configHelp(m, err)
+		return
+	}
+	err = bc.Repo.UserSetLocale(m, newLocale)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "An error ocurred saving your locale preference: "+err.Error())
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Changed locale for amounts from '%s' to '%s'.", locale.String(), newLocale.String()))
+}
+
+func localesHelp() string {
+	locales := []string{helpers.LOCALE_AUTO + " (guess from input, default)"}
+	for _, l := range helpers.LOCALES {
+		locales = append(locales, fmt.Sprintf("%s (%s)", l.Name, l.Description))
+	}
+	return strings.Join(locales, ", ")
+}
+
 func prettyTzOffset(tzOffset int) string {
 	if tzOffset < 0 {
 		return strconv.Itoa(tzOffset)
@@ -318,11 +399,14 @@ func (bc *BotController) deleteUserData(m *tb.Message) {
 
 	errors.handle1(bc.Repo.DeleteTransactions(m))
 	errors.handle1(bc.Repo.DeleteTemplates(m))
+	errors.handle1(bc.Repo.DeleteAccounts(m))
 
 	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_ADM, "", m.Chat.ID))
 	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_CUR, "", m.Chat.ID))
 	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_TAG, "", m.Chat.ID))
 	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_TZOFF, "", m.Chat.ID))
+	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_LOCALE, "", m.Chat.ID))
+	errors.handle1(bc.Repo.SetUserSetting(helpers.USERSET_COMMODITIES, "", m.Chat.ID))
 
 	bc.State.Clear(m)
 	errors.handle1(bc.Repo.DeleteUser(m))
@@ -320,3 +320,99 @@ func TestConfigAbout(t *testing.T) {
 		t.Errorf("Should contain repo link: %s", bot.LastSentWhat)
 	}
 }
+
+func TestConfigLocale(t *testing.T) {
+	// Test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config locale", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "current locale is set to 'auto'", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config locale xx", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "unknown locale 'xx'", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_LOCALE).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "bot::userSetting"`).
+		WithArgs(12345, helpers.USERSET_LOCALE, "de").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config locale de", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "from 'auto' to 'de'", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConfigCurrencyAllow(t *testing.T) {
+	// Test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config currency allow USD chf", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "the commodity 'chf' needs to start with a capital letter", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_COMMODITIES).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "bot::userSetting"`).
+		WithArgs(12345, helpers.USERSET_COMMODITIES, "USD CHF").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config currency allow USD CHF USD", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "only accepted in your default currency and USD, CHF", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_COMMODITIES).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("USD CHF"))
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config currency", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "only accepted in these currencies: USD, CHF", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_COMMODITIES).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	bc.commandConfig(&MockContext{M: &tb.Message{Text: "/config currency allow off", Chat: chat}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "accepted in any currency", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
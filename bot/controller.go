@@ -2,6 +2,7 @@ package bot
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -54,6 +55,7 @@ func (bc *BotController) AddBotAndStart(b IBot) {
 	}
 
 	b.Handle(tb.OnText, bc.handleTextState)
+	b.Handle(tb.OnDocument, bc.handleDocument)
 
 	bc.Logf(TRACE, nil, "Starting bot '%s'", b.Me().Username)
 
@@ -69,12 +71,19 @@ const (
 	CMD_START       = "start"
 	CMD_HELP        = "help"
 	CMD_CANCEL      = "cancel"
+	CMD_BACK        = "back"
 	CMD_SIMPLE      = "simple"
+	CMD_BALANCE     = "balance"
+	CMD_PRICE       = "price"
+	CMD_SPLIT       = "split"
+	CMD_SETTLE      = "settle"
 	CMD_LIST        = "list"
+	CMD_EXPORT      = "export"
 	CMD_ARCHIVE_ALL = "archiveAll"
 	CMD_DELETE_ALL  = "deleteAll"
 	CMD_SUGGEST     = "suggestions"
 	CMD_CONFIG      = "config"
+	CMD_ACCOUNTS    = "accounts"
 
 	CMD_ADM_NOTIFY = "admin_notify"
 	CMD_ADM_CRON   = "admin_cron"
@@ -90,11 +99,18 @@ func (bc *BotController) commandMappings() []*CMD {
 		{CommandAlias: []string{CMD_HELP}, Handler: bc.commandHelp, Help: "List this command help"},
 		{CommandAlias: []string{CMD_START}, Handler: bc.commandStart, Help: "Give introduction into this bot"},
 		{CommandAlias: []string{CMD_CANCEL}, Handler: bc.commandCancel, Help: "Cancel any running commands or transactions"},
-		{CommandAlias: []string{CMD_SIMPLE}, Handler: bc.commandCreateSimpleTx, Help: "Record a simple transaction, defaults to today; Can be omitted by sending amount directy", Optional: []string{"date"}},
+		{CommandAlias: []string{CMD_BACK}, Handler: bc.commandBack, Help: "Go back to the previous step of the currently running transaction"},
+		{CommandAlias: []string{CMD_SIMPLE}, Handler: bc.commandCreateSimpleTx, Help: "Record a simple transaction, defaults to today; Can be omitted by sending amount directy. '!' marks it as pending", Optional: []string{"!", "date"}},
+		{CommandAlias: []string{CMD_BALANCE}, Handler: bc.commandCreateBalanceTx, Help: "Record a balance assertion for an account, defaults to today", Optional: []string{"date"}},
+		{CommandAlias: []string{CMD_PRICE}, Handler: bc.commandPrice, Help: "Record the price of a commodity: /" + CMD_PRICE + " <commodity> <amount> [currency] [date]"},
+		{CommandAlias: []string{CMD_SPLIT}, Handler: bc.commandCreateSplitTx, Help: "Record a transaction split between you and others: /" + CMD_SPLIT + " <participant>[=<share>] ..."},
+		{CommandAlias: []string{CMD_SETTLE}, Handler: bc.commandSettle, Help: "Show the open balances of the participants of split transactions"},
 		{CommandAlias: CMD_COMMENT, Handler: bc.commandAddComment, Help: "Add arbitrary text to transaction list"},
 		{CommandAlias: CMD_TEMPLATE, Handler: bc.commandTemplates, Help: "Create and use template transactions"},
-		{CommandAlias: []string{CMD_LIST}, Handler: bc.commandList, Help: "List your recorded transactions or remove entries", Optional: []string{"archived", "dated", "numbered", "rm <number>"}},
+		{CommandAlias: []string{CMD_LIST}, Handler: bc.commandList, Help: "List your recorded transactions, remove or edit entries", Optional: []string{"archived", "pending", "dated", "numbered", "rm <number>", "edit <number>"}},
+		{CommandAlias: []string{CMD_EXPORT}, Handler: bc.commandExport, Help: "Get your recorded transactions as .beancount file, sorted by date. 'archive' archives the exported transactions", Optional: []string{EXPORT_OPTION_ARCHIVED, "from", "to", EXPORT_OPTION_ARCHIVE}},
 		{CommandAlias: []string{CMD_SUGGEST}, Handler: bc.commandSuggestions, Help: "List, add or remove suggestions"},
+		{CommandAlias: []string{CMD_ACCOUNTS}, Handler: bc.commandAccounts, Help: "List, add, close or seed your open accounts"},
 		{CommandAlias: []string{CMD_CONFIG}, Handler: bc.commandConfig, Help: "Bot configurations"},
 		{CommandAlias: []string{CMD_ARCHIVE_ALL}, Handler: bc.commandArchiveTransactions, Help: "Archive recorded transactions"},
 		{CommandAlias: []string{CMD_DELETE_ALL}, Handler: bc.commandDeleteTransactions, Help: "Permanently delete recorded transactions"},
@@ -112,6 +128,10 @@ func (bc *BotController) commandStart(c tb.Context) error {
 		"Please check the commands I will send to you next that are available to you. "+
 		"You can always reach the command help by typing /"+CMD_HELP, clearKeyboard())
 	bc.commandHelp(c)
+	if params := commandParams(c.Message().Text); len(params) == 1 && strings.HasPrefix(params[0], TEMPLATE_SHARE_PREFIX) {
+		// Opened through a link to a shared template
+		bc.importSharedTemplate(c.Message(), strings.TrimPrefix(params[0], TEMPLATE_SHARE_PREFIX), "")
+	}
 	return nil
 }
 
@@ -152,6 +172,7 @@ func (bc *BotController) commandCancel(c tb.Context) error {
 	tx := bc.State.GetType(c.Message())
 	hasState := tx != ST_NONE
 	bc.Logf(TRACE, c.Message(), "Clearing state. Had state? %t > '%s'", hasState, tx)
+	isTplEdit := bc.State.IsTplEdit(c.Message())
 
 	bc.State.Clear(c.Message())
 
@@ -159,6 +180,9 @@ func (bc *BotController) commandCancel(c tb.Context) error {
 	if hasState {
 		if tx == ST_TPL {
 			msg = "Your currently running template creation has been cancelled."
+			if isTplEdit {
+				msg = "Your currently running template edit has been cancelled. The template has been left unchanged."
+			}
 		} else {
 			msg = "Your currently running transaction has been cancelled."
 		}
@@ -167,6 +191,23 @@ func (bc *BotController) commandCancel(c tb.Context) error {
 	return nil
 }
 
+func (bc *BotController) commandBack(c tb.Context) error {
+	tx := bc.State.GetTx(c.Message())
+	if tx == nil {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), fmt.Sprintf("You currently don't have any transaction open to go back in.\nType /%s to get available commands.", CMD_HELP), clearKeyboard())
+		return nil
+	}
+	bc.Logf(TRACE, c.Message(), "Going back one step in transaction")
+	err := tx.Back()
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), "Going back did not work: "+err.Error())
+		return nil
+	}
+	hint := tx.NextHint(bc.Repo, c.Message())
+	bc.sendNextTxHint(hint, c.Message())
+	return nil
+}
+
 const MSG_UNFINISHED_STATE = "You have an unfinished operation running. Please finish it or /cancel it before starting a new one."
 
 type Sender struct {
@@ -192,14 +233,15 @@ func (bc *BotController) commandCreateSimpleTx(c tb.Context) error {
 		"I will guide you through.\n\n",
 		clearKeyboard(),
 	)
-	tx, err := bc.State.SimpleTx(c.Message(), bc.Repo.UserGetCurrency(c.Message())) // create new tx
+	tx, err := bc.State.SimpleTx(c.Message(), bc.Repo.UserGetCurrency(c.Message()), bc.Repo.UserGetTzOffset(c.Message())) // create new tx
 	if err != nil {
 		bc.Bot.SendSilent(bc, Recipient(c.Message()), "Something went wrong creating your transactions ("+err.Error()+"). Please check /help for usage."+
-			"\n\nYou can create a simple transaction using this command: /simple [date]\ne.g. /simple 2021-01-24\n"+
-			"The date parameter is non-mandatory, if not specified, today's date will be taken."+
+			"\n\nYou can create a simple transaction using this command: /simple [!] [date]\ne.g. /simple 2021-01-24 or /simple ! yesterday for a pending transaction\n"+
+			"The date parameter is non-mandatory, if not specified, today's date will be taken. Relative dates like 'yesterday', '-2' or 'last friday' are supported as well. "+
 			"Alternatively it is also possible to send an amount directly to start a new simple transaction.", clearKeyboard())
 		return nil
 	}
+	bc.applyUserPreferences(c.Message(), tx)
 	if tx.IsDone() {
 		bc.finishTransaction(c.Message(), tx)
 		return nil
@@ -209,6 +251,30 @@ func (bc *BotController) commandCreateSimpleTx(c tb.Context) error {
 	return nil
 }
 
+func (bc *BotController) commandCreateBalanceTx(c tb.Context) error {
+	state := bc.State.GetType(c.Message())
+	if state != ST_NONE {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), MSG_UNFINISHED_STATE)
+		return nil
+	}
+	bc.Logf(TRACE, c.Message(), "Creating balance assertion")
+	bc.Bot.SendSilent(bc, Recipient(c.Message()), "In the following steps we will record a balance assertion, "+
+		"checking the amount an account holds at the beginning of the given date.\n\n",
+		clearKeyboard(),
+	)
+	tx, err := bc.State.BalanceTx(c.Message(), bc.Repo.UserGetCurrency(c.Message()), bc.Repo.UserGetTzOffset(c.Message()))
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), "Something went wrong creating your balance assertion ("+err.Error()+"). Please check /help for usage."+
+			"\n\nYou can record a balance assertion using this command: /balance [date]\ne.g. /balance 2021-01-24\n"+
+			"The date parameter is non-mandatory, if not specified, today's date will be taken.", clearKeyboard())
+		return nil
+	}
+	bc.applyUserPreferences(c.Message(), tx)
+	hint := tx.NextHint(bc.Repo, c.Message())
+	bc.sendNextTxHint(hint, c.Message())
+	return nil
+}
+
 func (bc *BotController) commandAddComment(c tb.Context) error {
 	if bc.State.GetType(c.Message()) != ST_NONE {
 		bc.Logf(INFO, c.Message(), "commandAddComment while in another transaction")
@@ -242,15 +308,20 @@ func (bc *BotController) commandList(c tb.Context) error {
 	bc.Logf(TRACE, c.Message(), "Listing transactions")
 	command := strings.Split(c.Message().Text, " ")
 	isArchived := false
+	isPending := false
 	isDated := false
 	isNumbered := false
 	isDeleteCommand := false
+	isEditCommand := false
 	elementNumber := -1
 	if len(command) > 1 {
 		for _, option := range command[1:] {
 			if option == "archived" {
 				isArchived = true
 				continue
+			} else if option == "pending" {
+				isPending = true
+				continue
 			} else if option == "dated" {
 				isDated = true
 				continue
@@ -260,6 +331,9 @@ func (bc *BotController) commandList(c tb.Context) error {
 			} else if option == "rm" {
 				isDeleteCommand = true
 				continue
+			} else if option == "edit" {
+				isEditCommand = true
+				continue
 			} else {
 				var err error
 				elementNumber, err = strconv.Atoi(option)
@@ -275,6 +349,14 @@ func (bc *BotController) commandList(c tb.Context) error {
 		bc.Bot.SendSilent(bc, Recipient(c.Message()), "For removing a single element from the list, determine it's number by sending the command '/list numbered' and then removing an entry by sending '/list rm <number>'.", clearKeyboard())
 		return nil
 	}
+	if isEditCommand && (isDeleteCommand || isNumbered || isDated || elementNumber <= 0) {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), "For editing a single element from the list, determine it's number by sending the command '/list numbered' and then editing an entry by sending '/list edit <number>'.", clearKeyboard())
+		return nil
+	}
+	if isEditCommand && bc.State.GetType(c.Message()) != ST_NONE {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), MSG_UNFINISHED_STATE)
+		return nil
+	}
 	tx, err := bc.Repo.GetTransactions(c.Message(), isArchived)
 	if err != nil {
 		bc.Bot.SendSilent(bc, Recipient(c.Message()), "Something went wrong retrieving your transactions: "+err.Error(), clearKeyboard())
@@ -299,12 +381,20 @@ func (bc *BotController) commandList(c tb.Context) error {
 		bc.Bot.SendSilent(bc, Recipient(c.Message()), "Successfully deleted the list entry specified.", clearKeyboard())
 		return nil
 	}
+	if isEditCommand {
+		bc.listEditTransaction(c.Message(), tx, isArchived, elementNumber)
+		return nil
+	}
 	SEP := "\n"
 	txList := []string{}
 	txEntryNumber := 0
 	for _, t := range tx {
 		var dateComment string
 		txEntryNumber++
+		if isPending && !isPendingTransaction(t.Tx) {
+			// Skipped after counting, so that numbers match the complete list for removing or editing entries
+			continue
+		}
 		if isDated {
 			tzOffset := bc.Repo.UserGetTzOffset(c.Message())
 			timezoneOff := time.Duration(tzOffset) * time.Hour
@@ -327,6 +417,10 @@ func (bc *BotController) commandList(c tb.Context) error {
 		txList = append(txList, txMessage)
 	}
 	messageSplits := bc.MergeMessagesHonorSendLimit(txList, "\n")
+	if len(messageSplits) == 0 && isPending {
+		bc.Bot.SendSilent(bc, Recipient(c.Message()), "There are no pending transactions in your list.", clearKeyboard())
+		return nil
+	}
 	if len(messageSplits) == 0 {
 		archivedSuggestion := ""
 		if !isArchived {
@@ -342,6 +436,42 @@ func (bc *BotController) commandList(c tb.Context) error {
 	return nil
 }
 
+var pendingTransactionPattern = regexp.MustCompile(`(?m)^\d{4}-\d{2}-\d{2}\s+!(\s|$)`)
+
+// isPendingTransaction checks whether a list entry contains a transaction flagged as pending ('!')
+func isPendingTransaction(tx string) bool {
+	return pendingTransactionPattern.MatchString(tx)
+}
+
+func (bc *BotController) listEditTransaction(m *tb.Message, tx []*crud.TransactionResult, isArchived bool, elementNumber int) {
+	if elementNumber > len(tx) {
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while trying to edit a single transaction: "+
+			"the number you specified was too high. Please use a correct number as seen from '/list [archived] numbered'", clearKeyboard())
+		return
+	}
+	element := tx[elementNumber-1]
+	txData, err := bc.Repo.GetTransactionData(m, isArchived, element.Id)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while trying to edit a single transaction: "+err.Error(), clearKeyboard())
+		return
+	}
+	if txData == "" {
+		bc.Bot.SendSilent(bc, Recipient(m), "This list entry can't be edited, as it is a comment or has been recorded before editing was supported. "+
+			"You can remove it using '/list rm <number>' and record it again instead.", clearKeyboard())
+		return
+	}
+	editTx, err := bc.State.EditTx(m, txData, bc.Repo.UserGetCurrency(m), bc.Repo.UserGetTzOffset(m), element.Id, isArchived)
+	if err != nil {
+		bc.Logf(ERROR, m, "Restoring transaction for editing failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while trying to edit a single transaction: "+err.Error(), clearKeyboard())
+		return
+	}
+	bc.applyUserPreferences(m, editTx)
+	bc.Bot.SendSilent(bc, Recipient(m), "You are now editing the following transaction. If you don't want to change it, you can /cancel the editing.\n\n"+element.Tx, clearKeyboard())
+	hint := editTx.NextHint(bc.Repo, m)
+	bc.sendNextTxHint(hint, m)
+}
+
 func (bc *BotController) MergeMessagesHonorSendLimit(m []string, sep string) []string {
 	messages := []string{}
 	currentMessageBlock := ""
@@ -398,6 +528,11 @@ func (bc *BotController) commandSuggestions(c tb.Context) error {
 	return nil
 }
 
+func (bc *BotController) commandAccounts(c tb.Context) error {
+	bc.accountsHandler(c.Message())
+	return nil
+}
+
 func (bc *BotController) commandConfig(c tb.Context) error {
 	bc.configHandler(c.Message())
 	return nil
@@ -541,13 +676,18 @@ func (bc *BotController) matchesCommandWithoutLeadingSlash(m tb.Context) tb.Hand
 func (bc *BotController) handleTextState(c tb.Context) error {
 	state := bc.State.GetType(c.Message())
 	if state == ST_NONE {
-		if _, err := HandleFloat(c.Message()); err == nil { // Not in tx, but input would suffice for correct parsing of amount field of new tx
+		locale := bc.Repo.UserGetLocale(c.Message())
+		if bc.isQuickEntryAllowed(c.Message(), locale) {
+			bc.handleQuickEntry(c.Message(), locale)
+			return nil
+		} else if _, err := handleAmount(c.Message(), false, locale); err == nil { // Not in tx, but input would suffice for correct parsing of amount field of new tx
 			bc.Logf(DEBUG, c.Message(), "Creating new simple transaction as amount has been entered though not in tx")
-			_, err = bc.State.SimpleTx(c.Message(), bc.Repo.UserGetCurrency(c.Message())) // create new tx
+			tx, err := bc.State.SimpleTx(c.Message(), bc.Repo.UserGetCurrency(c.Message()), bc.Repo.UserGetTzOffset(c.Message())) // create new tx
 			if err != nil {
 				bc.Bot.SendSilent(bc, Recipient(c.Message()), "Something went wrong creating a new transaction: "+err.Error(), clearKeyboard())
 				return nil
 			}
+			bc.applyUserPreferences(c.Message(), tx)
 			bc.Bot.SendSilent(bc, Recipient(c.Message()), "Automatically created a new transaction for you. If you think this was a mistake you can /cancel it.", clearKeyboard())
 			bc.handleTextState(c)
 			return nil
@@ -568,6 +708,11 @@ func (bc *BotController) handleTextState(c tb.Context) error {
 		return nil
 	} else if state == ST_TX {
 		tx := bc.State.GetTx(c.Message())
+		if !bc.confirmAccountInput(c.Message(), tx) {
+			hint := tx.NextHint(bc.Repo, c.Message())
+			bc.sendNextTxHint(hint, c.Message())
+			return nil
+		}
 		_, err := tx.Input(c.Message())
 		if err != nil {
 			bc.Logf(WARN, c.Message(), "Invalid text state input: '%s'. Err: %s", c.Message().Text, err.Error())
@@ -595,6 +740,67 @@ func (bc *BotController) handleTextState(c tb.Context) error {
 	return nil
 }
 
+func (bc *BotController) isQuickEntryAllowed(m *tb.Message, locale *helpers.Locale) bool {
+	if !IsQuickEntry(m.Text, locale) {
+		return false
+	}
+	if m.Sender != nil && crud.IsGroupChat(m) && !strings.ContainsAny(m.Text, QUICK_ENTRY_TO+QUICK_ENTRY_FROM) {
+		// Don't interpret arbitrary group messages starting with a number as transactions
+		return false
+	}
+	return true
+}
+
+func (bc *BotController) handleQuickEntry(m *tb.Message, locale *helpers.Locale) {
+	entry, err := ParseQuickEntry(m.Text, bc.Repo.UserGetTzOffset(m), locale)
+	currency := bc.Repo.UserGetCurrency(m)
+	commodities := bc.Repo.UserGetCommodities(m)
+	if err == nil {
+		err = checkAllowedCurrencies(entry.Amount, commodities, currency)
+	}
+	if err != nil {
+		bc.Logf(DEBUG, m, "Parsing quick entry failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Your message could not be recorded as a transaction: "+err.Error()+
+			"\n\nThe format is '<amount> [<currency>] [<description>] [> <to account>] [< <from account>] [#<tag>] [<date>]', "+
+			"e.g. '12.50 Coffee shop > Expenses:Food:Coffee < Assets:Cash #trip 2026-10-15'.", clearKeyboard())
+		return
+	}
+	bc.Logf(DEBUG, m, "Creating new simple transaction from quick entry")
+	data := entry.Data()
+	if entry.From != "" || entry.To != "" {
+		accounts, err := bc.Repo.GetAccounts(m)
+		if err != nil {
+			bc.Logf(ERROR, m, "Could not check accounts against registry: %s", err.Error())
+		}
+		for _, specifier := range []string{helpers.FIELD_ACCOUNT_FROM, helpers.FIELD_ACCOUNT_TO} {
+			key := helpers.FqCacheKey(helpers.FIELD_ACCOUNT + ":" + specifier)
+			account := data[key]
+			if account == "" || len(accounts) == 0 || helpers.ArrayContains(accounts, account) {
+				continue
+			}
+			// Ask for accounts not in the registry interactively, where they can be confirmed
+			delete(data, key)
+			bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("The account '%s' is not open in your registry of accounts (/%s). Please enter it again or choose another one.", account, CMD_ACCOUNTS))
+		}
+	}
+	tx := bc.State.QuickTx(m, currency, data)
+	tx.SetLocale(locale)
+	tx.SetCommodities(commodities)
+	if tx.IsDone() {
+		bc.finishTransaction(m, tx)
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), "Automatically created a new transaction from your message. Please complete the missing parts. If you think this was a mistake you can /cancel it.", clearKeyboard())
+	hint := tx.NextHint(bc.Repo, m)
+	bc.sendNextTxHint(hint, m)
+}
+
+// applyUserPreferences sets the user's preferences for entering amounts on a newly created transaction
+func (bc *BotController) applyUserPreferences(m *tb.Message, tx Tx) {
+	tx.SetLocale(bc.Repo.UserGetLocale(m))
+	tx.SetCommodities(bc.Repo.UserGetCommodities(m))
+}
+
 func (bc *BotController) sendNextTxHint(hint *Hint, m *tb.Message) {
 	replyKeyboard := ReplyKeyboard(hint.KeyboardOptions)
 	bc.Logf(TRACE, m, "Sending hints for next step: %v", hint.KeyboardOptions)
@@ -616,7 +822,20 @@ func (bc *BotController) finishTransaction(m *tb.Message, tx Tx) {
 		return
 	}
 
-	err = bc.Repo.RecordTransaction(m.Chat.ID, transaction)
+	txData, err := tx.Serialize()
+	if err != nil {
+		bc.Logf(ERROR, m, "Something went wrong while serializing the transaction: "+err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while recording your transaction: "+err.Error(), clearKeyboard())
+		return
+	}
+
+	successMessage := "Successfully recorded your transaction."
+	if editTx, isEdit := tx.(*EditTx); isEdit {
+		err = bc.Repo.UpdateTransaction(m, editTx.IsArchived, editTx.ElementId, transaction, txData)
+		successMessage = "Successfully updated your transaction."
+	} else {
+		err = bc.Repo.RecordTransactionWithData(m.Chat.ID, transaction, txData)
+	}
 	if err != nil {
 		bc.Logf(ERROR, m, "Something went wrong while recording the transaction: "+err.Error())
 		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while recording your transaction: "+err.Error(), clearKeyboard())
@@ -630,11 +849,11 @@ func (bc *BotController) finishTransaction(m *tb.Message, tx Tx) {
 		// Don't return, instead continue flow (if recording was successful)
 	}
 
-	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully recorded your transaction.\n"+
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("%s\n"+
 		"You can get a list of all your transactions using /%s. "+
 		"With /%s you can delete all of them (e.g. once you copied them into your bookkeeping)."+
 		"\n\nYou can start a new transaction with /%s or type /%s to see all commands available.",
-		CMD_LIST, CMD_ARCHIVE_ALL, CMD_SIMPLE, CMD_HELP),
+		successMessage, CMD_LIST, CMD_ARCHIVE_ALL, CMD_SIMPLE, CMD_HELP),
 		clearKeyboard(),
 	)
 
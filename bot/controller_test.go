@@ -29,6 +29,15 @@ func TestTextHandlingWithoutPriorState(t *testing.T) {
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
 		WithArgs(chat.ID, helpers.USERSET_CUR).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("TEST_CURRENCY"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	// Check account registry
+	mock.
+		ExpectQuery(`SELECT "account" FROM "bot::account"`).
+		WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
 	// Finish
 	mock.
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
@@ -48,7 +57,7 @@ func TestTextHandlingWithoutPriorState(t *testing.T) {
 		WithArgs(chat.ID, today+` * "Buy something in the grocery store" #vacation2021
   Assets:Wallet                               -17.34 TEST_CURRENCY
   Expenses:Groceries
-`).
+`, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	// Cache handling on saving tx
 	mock.
@@ -83,6 +92,7 @@ func TestTextHandlingWithoutPriorState(t *testing.T) {
 	tx.Input(&tb.Message{Text: "Buy something in the grocery store"})                        // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})                                             // from
 	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Expenses:Groceries"}}) // to (via handleTextState)
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: POSTING_DONE}})         // no further postings
 
 	mock.
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
@@ -116,10 +126,18 @@ func TestStartTransactionWithPlainAmountThousandsSeparated(t *testing.T) {
 	bot := &MockBot{}
 	bc.AddBotAndStart(bot)
 
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
 	mock.
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
 		WithArgs(chat.ID, helpers.USERSET_CUR).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("TEST_CURRENCY"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
 
 	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "1,000,000"}})
 
@@ -132,6 +150,77 @@ func TestStartTransactionWithPlainAmountThousandsSeparated(t *testing.T) {
 	}
 }
 
+func TestStartTransactionWithQuickEntry(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	// complete quick entry
+	delete(crud.CACHE_LOCAL, chat.ID)
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_COMMODITIES).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TAG).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("vacation2021"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectExec(`INSERT INTO "bot::transaction"`).WithArgs(chat.ID, `2026-10-15 * "Coffee shop" #trip
+  Assets:Cash                                 -12.50 EUR
+  Expenses:Food:Coffee
+`, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "12.50 Coffee shop > Expenses:Food:Coffee < Assets:Cash #trip 2026-10-15"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully recorded your transaction", "")
+	bc.State.Clear(&tb.Message{Chat: chat})
+
+	// missing parts are asked for
+	delete(crud.CACHE_LOCAL, chat.ID)
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_COMMODITIES).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
+	mock.ExpectQuery(`SELECT "type", "value"`).WithArgs(chat.ID).WillReturnRows(sqlmock.NewRows([]string{"type", "value"}))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "12.50 Coffee shop > Expenses:Food:Coffee"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "*from*", "should ask for the missing account")
+	debugString := bc.State.txStates[12345].Debug()
+	helpers.TestStringContains(t, debugString, "description::Coffee shop", "contain parsed description")
+	bc.State.Clear(&tb.Message{Chat: chat})
+
+	// in group chats quick entries need an account marker
+	sender := &tb.User{ID: 999}
+	mock.ExpectQuery(`SELECT "value"`).WithArgs(chat.ID, helpers.USERSET_LOCALE).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value"`).WithArgs(chat.ID, helpers.USERSET_OMITCMDSLASH).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Sender: sender, Text: "3 people are coming tonight"}})
+	helpers.TestExpect(t, bc.State.GetType(&tb.Message{Chat: chat}), ST_NONE, "group message should not start a transaction")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestTransactionDeletion(t *testing.T) {
 	// create test dependencies
 	chat := &tb.Chat{ID: 12345}
@@ -259,6 +348,100 @@ func TestTransactionsListArchivedDated(t *testing.T) {
 	}
 }
 
+func TestTransactionListEdit(t *testing.T) {
+	// create test dependencies
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	crud.TEST_MODE = true
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	tx, _ := CreateSimpleTx("", TEMPLATE_SIMPLE_DEFAULT)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"17.34", "Buy something", "Assets:Wallet", "Expenses:Groceries", POSTING_DONE} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	tx.FillTemplate("EUR", "", 0)
+	txData, _ := tx.Serialize()
+
+	// entries without data can't be edited
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(12345, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).AddRow(123, "; comment", ""))
+	mock.ExpectQuery(`SELECT "txData" FROM "bot::transaction"`).WithArgs(12345, false, 123).
+		WillReturnRows(sqlmock.NewRows([]string{"txData"}).AddRow(nil))
+	bc.commandList(&MockContext{M: &tb.Message{Chat: chat, Text: "/list edit 1"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "can't be edited", "entries without data should not be editable")
+	helpers.TestExpect(t, bc.State.GetType(&tb.Message{Chat: chat}), ST_NONE, "")
+
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(12345, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).AddRow(123, "; comment", "").AddRow(124, "tx2", ""))
+	mock.ExpectQuery(`SELECT "txData" FROM "bot::transaction"`).WithArgs(12345, false, 124).
+		WillReturnRows(sqlmock.NewRows([]string{"txData"}).AddRow(txData))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandList(&MockContext{M: &tb.Message{Chat: chat, Text: "/list edit 2"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please select the *field*", "should ask for the field to edit")
+
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "amount"}})
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "20"}})
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_TAG).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(12345, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectExec(`UPDATE "bot::transaction"`).WithArgs(12345, false, 124, `2022-04-11 * "Buy something"
+  Assets:Wallet                               -20.00 EUR
+  Expenses:Groceries
+`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: EDIT_SAVE}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully updated your transaction", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCommandBack(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	bc.commandBack(&MockContext{M: &tb.Message{Chat: chat, Text: "/back"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "don't have any transaction open", "")
+
+	bc.State.SimpleTx(&tb.Message{Chat: chat, Text: "/simple"}, "EUR", 0)
+	bc.commandBack(&MockContext{M: &tb.Message{Chat: chat, Text: "/back"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "no previous step", "")
+
+	mock.ExpectQuery(`SELECT "type", "value"`).WithArgs(chat.ID).WillReturnRows(sqlmock.NewRows([]string{"type", "value"}))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "12.34"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "*description*", "")
+
+	bc.commandBack(&MockContext{M: &tb.Message{Chat: chat, Text: "/back"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "*amount*", "amount should be asked for again")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestWritingComment(t *testing.T) {
 	// create test dependencies
 	crud.TEST_MODE = true
@@ -372,6 +555,14 @@ func TestTimezoneOffsetForAutomaticDate(t *testing.T) {
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
 		WithArgs(chat.ID, helpers.USERSET_CUR).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("TEST_CURRENCY"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("-24"))
+	mock.
+		ExpectQuery(`SELECT "account" FROM "bot::account"`).
+		WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
 	mock.
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
 		WithArgs(chat.ID, helpers.USERSET_CUR).
@@ -390,7 +581,7 @@ func TestTimezoneOffsetForAutomaticDate(t *testing.T) {
 		WithArgs(chat.ID, yesterday_tzCorrection+` * "Buy something in the grocery store" #vacation2021
   Assets:Wallet                               -17.34 TEST_CURRENCY
   Expenses:Groceries
-`).
+`, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	bc := NewBotController(db)
@@ -404,6 +595,7 @@ func TestTimezoneOffsetForAutomaticDate(t *testing.T) {
 	tx.Input(&tb.Message{Text: "Buy something in the grocery store"})                     // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})                                          // from
 	bc.handleTextState(&MockContext{&tb.Message{Chat: chat, Text: "Expenses:Groceries"}}) // to (via handleTextState)
+	bc.handleTextState(&MockContext{&tb.Message{Chat: chat, Text: POSTING_DONE}})         // no further postings
 
 	// After the first tx is done, send some command
 	m := &MockContext{M: &tb.Message{Chat: chat, Sender: &tb.User{ID: chat.ID}}}
@@ -449,3 +641,187 @@ func TestCommandWithoutLeadingSlash(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestBalanceAssertionCommand(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	delete(crud.CACHE_LOCAL, chat.ID)
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).AddRow("account:balance", "Assets:Bank"))
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("account:balance", "Assets:Bank").
+			AddRow("account:from", "Assets:Cash").
+			AddRow("account:to", "someAccount"))
+	bc.commandCreateBalanceTx(&MockContext{M: &tb.Message{Chat: chat, Text: "/balance 2022-04-11"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please enter the *account* to assert the balance *of*", "")
+	suggestions := []string{}
+	for _, row := range bot.LastSentOptions[0].(*tb.ReplyMarkup).ReplyKeyboard {
+		suggestions = append(suggestions, row[0].Text)
+	}
+	helpers.TestExpectArrEq(t, suggestions, []string{"Assets:Bank", "Assets:Cash"}, "all valid accounts should be suggested")
+
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Assets:Cash"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please enter the *amount* of money the account holds", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TAG).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectExec(`INSERT INTO "bot::transaction"`).
+		WithArgs(chat.ID, "2022-04-11 balance Assets:Cash                123.45 EUR\n", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "123.45"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully recorded your transaction", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPriceCommand(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	delete(crud.CACHE_LOCAL, chat.ID)
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).AddRow("price:", "VTI USD"))
+	bc.commandPrice(&MockContext{M: &tb.Message{Chat: chat, Text: "/price"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Usage help for /price", "")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "recently used commodity pairs:\nVTI USD", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandPrice(&MockContext{M: &tb.Message{Chat: chat, Text: "/price vti 210.55 USD"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Error executing your command: the commodity 'vti'", "")
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_LOCALE).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("de"))
+	mock.ExpectExec(`INSERT INTO "bot::transaction"`).
+		WithArgs(chat.ID, "2022-04-11 price VTI                         1234.50 USD\n").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).AddRow("price:", "VTI USD"))
+	mock.ExpectExec(`UPDATE "bot::cache"`).WithArgs(chat.ID, "price:", "VTI USD").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).AddRow("price:", "VTI USD"))
+	bc.commandPrice(&MockContext{M: &tb.Message{Chat: chat, Text: "/price VTI 1.234,50 2022-04-11"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully added the price of VTI", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPendingTransactions(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandCreateSimpleTx(&MockContext{M: &tb.Message{Chat: chat, Text: "/simple ! 2022-04-11"}})
+	debugString := bc.State.txStates[12345].Debug()
+	helpers.TestStringContains(t, debugString, "flag::!", "pending flag should be set")
+	helpers.TestStringContains(t, debugString, "date::2022-04-11", "date should be set after flag")
+	bc.State.Clear(&tb.Message{Chat: chat})
+
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).
+			AddRow(1, "2022-04-10 * \"Completed\"\n  Assets:Wallet  -1.00 EUR\n  Expenses:Food\n", "").
+			AddRow(2, "; comment with 2022-04-10 ! in it\n", "").
+			AddRow(3, "2022-04-11 ! \"Pending\"\n  Assets:Wallet  -2.00 EUR\n  Expenses:Food\n", ""))
+	bc.commandList(&MockContext{M: &tb.Message{Chat: chat, Text: "/list pending numbered"}})
+	helpers.TestExpect(t, fmt.Sprintf("%v", bot.LastSentWhat), "3) 2022-04-11 ! \"Pending\"\n  Assets:Wallet  -2.00 EUR\n  Expenses:Food\n", "only pending transactions should be listed, keeping their numbers")
+
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).AddRow(1, "2022-04-10 * \"Completed\"\n", ""))
+	bc.commandList(&MockContext{M: &tb.Message{Chat: chat, Text: "/list pending"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "no pending transactions", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSettleCommand(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}))
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}))
+	bc.commandSettle(&MockContext{M: &tb.Message{Chat: chat, Text: "/settle"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "no open balances", "")
+
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).
+			AddRow(2, "2022-04-12 * \"Cinema\"\n  Assets:Receivable:Bob  -12.00 EUR\n  Expenses:Fun\n", ""))
+	mock.ExpectQuery(`SELECT "id", "value", "created" FROM "bot::transaction"`).WithArgs(chat.ID, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).
+			AddRow(1, "2022-04-11 * \"Pizza\"\n  Assets:Wallet  -10.00 EUR\n  Assets:Receivable:Alice  3.33 EUR\n  Expenses:Food\n", ""))
+	bc.commandSettle(&MockContext{M: &tb.Message{Chat: chat, Text: "/settle"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Alice owes you 3.33 EUR\nYou owe Bob 12.00 EUR", "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// isAllowedCurrency checks the currency against the user's allowed commodities. The default currency is always allowed.
+func isAllowedCurrency(currency string, commodities []string, defaultCurrency string) error {
+	if len(commodities) == 0 || currency == defaultCurrency || c.ArrayContains(commodities, currency) {
+		return nil
+	}
+	return fmt.Errorf("the currency '%s' is not in your list of allowed currencies (%s). You can change the list using '/%s currency allow'",
+		currency, strings.Join(append([]string{defaultCurrency}, commodities...), ", "), CMD_CONFIG)
+}
+
+// checkAllowedCurrencies checks the currencies of a handled amount, including the one of its price annotation
+func checkAllowedCurrencies(value string, commodities []string, defaultCurrency string) error {
+	_, currency, annotation, err := splitAmount(strings.ReplaceAll(value, FORMATTER_PLACEHOLDER, ""))
+	if err != nil {
+		return err
+	}
+	currencies := []string{currency}
+	if annotation != nil {
+		currencies = append(currencies, annotation.Currency)
+	}
+	for _, currency := range currencies {
+		if currency == "" {
+			continue
+		}
+		if err := isAllowedCurrency(currency, commodities, defaultCurrency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withCurrency adds the currency to a handled amount not specifying one itself
+func withCurrency(value, currency string) string {
+	amount, amountCurrency, annotation, err := splitAmount(strings.ReplaceAll(value, FORMATTER_PLACEHOLDER, ""))
+	if err != nil || amountCurrency != "" || currency == "" {
+		return value
+	}
+	value = FORMATTER_PLACEHOLDER + ParseAmount(amount, currency) + " " + currency
+	if annotation != nil {
+		value += " " + annotation.String()
+	}
+	return value
+}
+
+// SetCommodities sets the currencies accepted for amounts besides the suggested currency. If empty, any valid commodity is accepted.
+func (tx *SimpleTx) SetCommodities(commodities []string) {
+	tx.commodities = commodities
+}
+
+// allowedCommodities returns the currencies accepted for the amount field besides the suggested currency
+func (tx *SimpleTx) allowedCommodities(f *TemplateField) []string {
+	if len(tx.commodities) == 0 || f.Currency == "" {
+		return tx.commodities
+	}
+	// The currency given by the template is accepted as well
+	return append([]string{f.Currency}, tx.commodities...)
+}
+
+// selectCurrency handles a currency selected from the keyboard instead of an amount. The amount is then recorded in this currency.
+func (tx *SimpleTx) selectCurrency(f *TemplateField, m *tb.Message) (isSelection bool, err error) {
+	currency := strings.TrimSpace(m.Text)
+	if c.IsValidCommodity(currency) != nil {
+		return false, nil
+	}
+	if err := isAllowedCurrency(currency, tx.allowedCommodities(f), tx.userCurrencySuggestion); err != nil {
+		return true, err
+	}
+	tx.selectedCurrency = currency
+	return true, nil
+}
+
+// amountCurrency returns the currency explicitly given for the first amount of the transaction
+func (tx *SimpleTx) amountCurrency() string {
+	for _, f := range ParseTemplateFields(tx.template, "") {
+		if f.FieldName != c.FIELD_AMOUNT {
+			continue
+		}
+		_, currency, _, err := splitAmount(strings.ReplaceAll(tx.data[f.FieldIdentifierForValue()], FORMATTER_PLACEHOLDER, ""))
+		if err == nil && currency != "" {
+			return currency
+		}
+	}
+	return ""
+}
+
+// hintAmount offers the default currency, the ones used recently and the allowed ones to record the amount in
+func (tx *SimpleTx) hintAmount(r *crud.Repo, m *tb.Message, i *Input) *Hint {
+	if tx.selectedCurrency != "" {
+		i.hint.Prompt += fmt.Sprintf("\n\nThe amount will be recorded in *%s*.", escapeMarkdownValue(tx.selectedCurrency))
+		return i.hint
+	}
+	if r == nil {
+		return i.hint
+	}
+	recent, err := r.GetCacheHints(m, c.FqCacheKey(c.FIELD_CURRENCY))
+	if err != nil {
+		crud.LogDbf(r, ERROR, m, "Error occurred getting cached currencies: %s", err.Error())
+	}
+	commodities := tx.allowedCommodities(&i.field)
+	options := []string{}
+	for _, currency := range append(append([]string{i.field.Currency, tx.userCurrencySuggestion}, recent...), commodities...) {
+		if currency == "" || c.ArrayContains(options, currency) || isAllowedCurrency(currency, commodities, tx.userCurrencySuggestion) != nil {
+			continue
+		}
+		options = append(options, currency)
+	}
+	if len(options) > 1 {
+		i.hint.Prompt += "\n\nTo record the amount in another currency, enter it after the amount or select it from the list first."
+		i.hint.KeyboardOptions = options
+	}
+	return i.hint
+}
+
+// Conversions annotate an amount with the total of another amount of the transaction, e.g. '${-amount:sent} @@ ${amount:received}',
+// so that transfers between accounts of different currencies balance.
+var conversionPattern = regexp.MustCompile(`\$\{([^}]+)\}\s*@@\s*\$\{([^}]+)\}`)
+
+// renderConversions fills the total price of conversions. Conversions are left out if both amounts are given in the same currency
+// (or the annotated amount already carries a price annotation).
+func (tx *SimpleTx) renderConversions(template, defaultCurrency string) string {
+	return conversionPattern.ReplaceAllStringFunc(template, func(conversion string) string {
+		rawFields := conversionPattern.FindStringSubmatch(conversion)
+		annotated, total := ParseTemplateField(rawFields[1], ""), ParseTemplateField(rawFields[2], "")
+		if annotated.FieldName != c.FIELD_AMOUNT || total.FieldName != c.FIELD_AMOUNT {
+			return conversion
+		}
+		annotatedPlaceholder := fmt.Sprintf("${%s}", rawFields[1])
+		_, annotatedCurrency, annotation, err := splitAmount(strings.ReplaceAll(tx.data[annotated.FieldIdentifierForValue()], FORMATTER_PLACEHOLDER, ""))
+		if err != nil || annotation != nil {
+			return annotatedPlaceholder
+		}
+		totalAmount, totalCurrency, _, err := splitAmount(strings.ReplaceAll(tx.data[total.FieldIdentifierForValue()], FORMATTER_PLACEHOLDER, ""))
+		if err != nil {
+			// e.g. skipped optional amount
+			return annotatedPlaceholder
+		}
+		if annotatedCurrency == "" {
+			annotatedCurrency = defaultCurrency
+		}
+		if totalCurrency == "" {
+			totalCurrency = defaultCurrency
+		}
+		if annotatedCurrency == totalCurrency {
+			return annotatedPlaceholder
+		}
+		return fmt.Sprintf("%s %s %s %s", annotatedPlaceholder, PRICE_TOTAL, ParseAmount(totalAmount.Abs(), totalCurrency), totalCurrency)
+	})
+}
@@ -0,0 +1,113 @@
+package bot_test
+
+import (
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestCurrencyValidation(t *testing.T) {
+	for _, invalid := range []string{"12 eur", "12 €", "12 USD @ 0.92 eur"} {
+		if _, err := bot.HandleFloat(&tb.Message{Text: invalid}); err == nil {
+			t.Errorf("Currency of '%s' should be rejected", invalid)
+		}
+	}
+
+	tx, _ := bot.CreateSimpleTx("EUR", `${date} * "Groceries"
+  Assets:Wallet ${-amount}
+  Expenses:Groceries`)
+	tx.SetDate("2022-04-11", 0)
+	tx.SetCommodities([]string{"USD"})
+	for _, notAllowed := range []string{"12 CHF", "12 USD @ 0.95 CHF", "CHF"} {
+		if _, err := tx.Input(&tb.Message{Text: notAllowed}); err == nil {
+			t.Errorf("'%s' should be rejected as the currency is not allowed", notAllowed)
+		}
+	}
+	tx.Input(&tb.Message{Text: "12 USD @ 0.92 EUR"})
+	templated, _ := tx.FillTemplate("EUR", "", 0)
+	helpers.TestStringContains(t, templated, "-12.00 USD @ 0.92 EUR", "allowed and default currency should be accepted")
+	helpers.TestExpect(t, tx.CacheData()["currency:"], "USD", "explicitly given currency should be cached")
+}
+
+func TestCurrencySelection(t *testing.T) {
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	r := crud.NewRepo(db)
+	delete(crud.CACHE_LOCAL, chat.ID)
+
+	tx, _ := bot.CreateSimpleTx("EUR", `${date} * "Sushi"
+  Assets:Wallet ${-amount}
+  Expenses:Food`)
+	tx.SetDate("2022-04-11", 0)
+	tx.SetCommodities([]string{"JPY", "USD"})
+
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("currency:", "USD").
+			AddRow("currency:", "GBP"))
+	hint := tx.NextHint(r, &tb.Message{Chat: chat})
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{"EUR", "USD", "JPY"}, "default, recently used and allowed currencies should be offered")
+
+	isDone, err := tx.Input(&tb.Message{Text: "JPY"})
+	if err != nil || isDone {
+		t.Errorf("Selecting a currency should be accepted and not finish the transaction: %v", err)
+	}
+	hint = tx.NextHint(r, &tb.Message{Chat: chat})
+	helpers.TestStringContains(t, hint.Prompt, "recorded in *JPY*", "the amount should be asked for again in the selected currency")
+	tx.Input(&tb.Message{Text: "1200"})
+
+	templated, _ := tx.FillTemplate("EUR", "", 0)
+	helpers.TestExpect(t, templated, `2022-04-11 * "Sushi"
+  Assets:Wallet                             -1200 JPY
+  Expenses:Food
+`, "")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCurrencyConversion(t *testing.T) {
+	template := `${date} * "Transfer"
+  Assets:Checking:EUR ${-amount:sent:the money *sent*} @@ ${amount:received:the money *received*:USD}
+  Assets:Checking:USD ${amount:received:the money *received*:USD}`
+
+	tx, _ := bot.CreateSimpleTx("EUR", template)
+	tx.SetDate("2022-04-11", 0)
+	hint := tx.NextHint(nil, nil)
+	helpers.TestStringContains(t, hint.Prompt, "the money *received*", "each amount should be asked for with its own hint")
+	helpers.TestStringContains(t, hint.Prompt, "12.34 USD", "each amount should be asked for in its own currency")
+	tx.Input(&tb.Message{Text: "108.5"})
+	hint = tx.NextHint(nil, nil)
+	helpers.TestStringContains(t, hint.Prompt, "12.34 EUR", "")
+	tx.Input(&tb.Message{Text: "100"})
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Transfer"
+  Assets:Checking:EUR                        -100.00 EUR @@ 108.50 USD
+  Assets:Checking:USD                         108.50 USD
+`, "transaction should balance across currencies")
+
+	// No conversion needed for amounts in the same currency
+	tx, _ = bot.CreateSimpleTx("EUR", template)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "100 EUR"})
+	tx.Input(&tb.Message{Text: "100"})
+	templated, _ = tx.FillTemplate("EUR", "", 0)
+	helpers.TestExpect(t, templated, `2022-04-11 * "Transfer"
+  Assets:Checking:EUR                        -100.00 EUR
+  Assets:Checking:USD                         100.00 EUR
+`, "conversion should be left out")
+}
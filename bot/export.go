@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// Options of the export command: '/export [archived] [from] [to] [archive]'
+const (
+	EXPORT_OPTION_ARCHIVED = "archived" // export archived transactions instead of the open ones
+	EXPORT_OPTION_ARCHIVE  = "archive"  // archive the exported transactions afterwards
+)
+
+type ExportOptions struct {
+	IsArchived bool
+	Archive    bool
+	From       string
+	To         string
+}
+
+// ParseExportOptions parses the options of the export command. Dates are inclusive and can be given relatively, e.g. '-7'.
+func ParseExportOptions(params []string, today time.Time) (*ExportOptions, error) {
+	opts := &ExportOptions{}
+	for _, param := range params {
+		switch param {
+		case EXPORT_OPTION_ARCHIVED:
+			opts.IsArchived = true
+		case EXPORT_OPTION_ARCHIVE:
+			opts.Archive = true
+		default:
+			if opts.To != "" {
+				return nil, fmt.Errorf("please provide at most two dates ('from' and 'to')")
+			}
+			date, err := parseExportDate(param, opts.From, today)
+			if err != nil {
+				return nil, fmt.Errorf("the option '%s' could not be recognized: %s", param, err.Error())
+			}
+			if opts.From == "" {
+				opts.From = date
+			} else {
+				opts.To = date
+			}
+		}
+	}
+	if opts.IsArchived && opts.Archive {
+		return nil, fmt.Errorf("archived transactions can't be archived again")
+	}
+	if opts.From != "" && opts.To != "" && opts.From > opts.To {
+		return nil, fmt.Errorf("the date 'from' (%s) needs to be before 'to' (%s)", opts.From, opts.To)
+	}
+	return opts, nil
+}
+
+var (
+	exportDayPattern      = regexp.MustCompile(`^\d{1,2}$`)
+	exportMonthDayPattern = regexp.MustCompile(`^\d{2}-?\d{2}$`)
+)
+
+// parseExportDate parses a date of the export range. Dates given without year (or month) are usually resolved to the past.
+// For the end of the range ('to'), they are resolved to the first such date from 'from' on instead, which may lie in the future.
+func parseExportDate(param, from string, today time.Time) (string, error) {
+	date, err := ParseDateRelativeTo(param, today)
+	if err != nil || from == "" || date >= from {
+		return date, err
+	}
+	fromDate, err := time.Parse(h.BEANCOUNT_DATE_FORMAT, from)
+	if err != nil {
+		return "", err
+	}
+	if exportDayPattern.MatchString(param) {
+		return ParseDateRelativeTo(param, fromDate.AddDate(0, 1, 0))
+	}
+	if exportMonthDayPattern.MatchString(param) {
+		return ParseDateRelativeTo(param, fromDate.AddDate(1, 0, 0))
+	}
+	return date, nil
+}
+
+var transactionDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// transactionDate returns the date of a list entry. Entries without date (e.g. comments) are dated by their recording.
+func transactionDate(t *crud.TransactionResult) string {
+	if date := transactionDatePattern.FindString(strings.TrimSpace(t.Tx)); date != "" {
+		return date
+	}
+	if len(t.Date) >= len(h.BEANCOUNT_DATE_FORMAT) {
+		return t.Date[:len(h.BEANCOUNT_DATE_FORMAT)]
+	}
+	return ""
+}
+
+// SelectExportTransactions returns the transactions dated within the options' range, sorted by date.
+// Transactions of the same date keep the order they have been recorded in.
+func SelectExportTransactions(transactions []*crud.TransactionResult, opts *ExportOptions) []*crud.TransactionResult {
+	selected := []*crud.TransactionResult{}
+	for _, t := range transactions {
+		date := transactionDate(t)
+		if (opts.From != "" && date < opts.From) || (opts.To != "" && date > opts.To) {
+			continue
+		}
+		selected = append(selected, t)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return transactionDate(selected[i]) < transactionDate(selected[j])
+	})
+	return selected
+}
+
+func (bc *BotController) commandExport(c tb.Context) error {
+	m := c.Message()
+	bc.Logf(TRACE, m, "Exporting transactions")
+	tzOffset := bc.Repo.UserGetTzOffset(m)
+	opts, err := ParseExportOptions(commandParams(m.Text), Today(tzOffset))
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Error executing your command: %s\n\nUsage help for /%s:\n/%s [%s] [from] [to] [%s]\n\n"+
+			"e.g. '/%s 2026-10-01 2026-10-31 %s' exports the transactions of October and archives them afterwards.",
+			err.Error(), CMD_EXPORT, CMD_EXPORT, EXPORT_OPTION_ARCHIVED, EXPORT_OPTION_ARCHIVE, CMD_EXPORT, EXPORT_OPTION_ARCHIVE), clearKeyboard())
+		return nil
+	}
+	transactions, err := bc.Repo.GetTransactions(m, opts.IsArchived)
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong retrieving your transactions: "+err.Error(), clearKeyboard())
+		return nil
+	}
+	selected := SelectExportTransactions(transactions, opts)
+	if len(selected) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There are no transactions to export. Please check /%s.", CMD_LIST), clearKeyboard())
+		return nil
+	}
+
+	entries := []string{}
+	ids := []int{}
+	for _, t := range selected {
+		entries = append(entries, strings.TrimRight(t.Tx, "\n")+"\n")
+		ids = append(ids, t.Id)
+	}
+	caption := fmt.Sprintf("Your %d transactions, sorted by date.", len(selected))
+	if !opts.IsArchived && !opts.Archive {
+		caption += fmt.Sprintf(" To archive them after exporting, add the option '%s'.", EXPORT_OPTION_ARCHIVE)
+	}
+	_, err = bc.Bot.SendSilent(bc, Recipient(m), &tb.Document{
+		File:     tb.FromReader(strings.NewReader(strings.Join(entries, "\n"))),
+		FileName: fmt.Sprintf("transactions-%s.beancount", Today(tzOffset).Format(h.BEANCOUNT_DATE_FORMAT)),
+		MIME:     "text/plain",
+		Caption:  caption,
+	})
+	if err != nil {
+		// Transactions are only archived once they have been sent
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong sending your transactions. Nothing has been archived.", clearKeyboard())
+		return nil
+	}
+	if !opts.Archive {
+		return nil
+	}
+	count, err := bc.Repo.ArchiveTransactionsById(m, ids)
+	if err != nil {
+		bc.Logf(ERROR, m, "Archiving exported transactions failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong archiving your exported transactions: "+err.Error(), clearKeyboard())
+		return nil
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Archived the %d exported transactions. Transactions not exported are kept in your /%s.", count, CMD_LIST), clearKeyboard())
+	return nil
+}
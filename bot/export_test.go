@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestParseExportOptions(t *testing.T) {
+	today := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	opts, err := ParseExportOptions([]string{"2026-10-01", "2026-10-31", "archive"}, today)
+	helpers.TestExpect(t, err, nil, "")
+	helpers.TestExpect(t, *opts, ExportOptions{Archive: true, From: "2026-10-01", To: "2026-10-31"}, "options")
+
+	_, err = ParseExportOptions([]string{"archived", "archive"}, today)
+	helpers.TestExpect(t, err.Error(), "archived transactions can't be archived again", "")
+
+	_, err = ParseExportOptions([]string{"2026-10-31", "2026-10-01"}, today)
+	helpers.TestExpect(t, err.Error(), "the date 'from' (2026-10-31) needs to be before 'to' (2026-10-01)", "")
+
+	_, err = ParseExportOptions([]string{"2026-10-01", "2026-10-02", "2026-10-03"}, today)
+	helpers.TestExpect(t, err.Error(), "please provide at most two dates ('from' and 'to')", "")
+
+	opts, err = ParseExportOptions([]string{"10-01", "10-31"}, today)
+	helpers.TestExpect(t, err, nil, "")
+	helpers.TestExpect(t, *opts, ExportOptions{From: "2026-10-01", To: "2026-10-31"}, "'to' without year may lie in the future")
+
+	opts, err = ParseExportOptions([]string{"2025-12-01", "0131"}, today)
+	helpers.TestExpect(t, err, nil, "")
+	helpers.TestExpect(t, *opts, ExportOptions{From: "2025-12-01", To: "2026-01-31"}, "'to' without year is resolved from 'from' on")
+
+	opts, err = ParseExportOptions([]string{"10", "31"}, today)
+	helpers.TestExpect(t, err, nil, "")
+	helpers.TestExpect(t, *opts, ExportOptions{From: "2026-10-10", To: "2026-10-31"}, "'to' without month may lie in the future")
+
+	opts, err = ParseExportOptions([]string{"-7", "yesterday"}, today)
+	helpers.TestExpect(t, err, nil, "")
+	helpers.TestExpect(t, *opts, ExportOptions{From: "2026-10-10", To: "2026-10-16"}, "relative dates")
+
+	_, err = ParseExportOptions([]string{"everything"}, today)
+	helpers.TestStringContains(t, err.Error(), "the option 'everything' could not be recognized", "")
+}
+
+func TestSelectExportTransactions(t *testing.T) {
+	transactions := []*crud.TransactionResult{
+		{Id: 1, Tx: "2026-10-12 * \"Lunch\"\n", Date: "2026-10-12 12:00:00"},
+		{Id: 2, Tx: "; Check balance\n", Date: "2026-10-02 08:00:00"},
+		{Id: 3, Tx: "2026-09-28 * \"Rent\"\n", Date: "2026-10-05 10:00:00"},
+		{Id: 4, Tx: "2026-10-02 * \"Coffee\"\n", Date: "2026-10-03 09:00:00"},
+	}
+	ids := func(selected []*crud.TransactionResult) (ids []int) {
+		for _, t := range selected {
+			ids = append(ids, t.Id)
+		}
+		return
+	}
+	helpers.TestExpect(t, fmt.Sprint(ids(SelectExportTransactions(transactions, &ExportOptions{}))), "[3 2 4 1]", "sorted by date, same dates keep recorded order")
+	helpers.TestExpect(t, fmt.Sprint(ids(SelectExportTransactions(transactions, &ExportOptions{From: "2026-10-01", To: "2026-10-02"}))), "[2 4]", "date range")
+}
+
+func TestCommandExport(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id", "value", "created" FROM "bot::transaction"`)).
+		WithArgs(chat.ID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}).
+			AddRow(7, "2026-10-12 * \"Lunch\"\n  Assets:Cash -12.00 EUR\n  Expenses:Food\n", "2026-10-12 12:00:00").
+			AddRow(8, "2026-09-28 * \"Rent\"\n  Assets:Checking -800.00 EUR\n  Expenses:Rent\n", "2026-10-05 10:00:00").
+			AddRow(9, "2026-11-01 * \"Coffee\"\n  Assets:Cash -3.00 EUR\n  Expenses:Coffee\n", "2026-11-01 09:00:00"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "bot::transaction"`)).
+		WithArgs(chat.ID, 8, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	bc.commandExport(&MockContext{M: &tb.Message{Chat: chat, Text: "/export 2026-09-01 2026-10-31 archive"}})
+
+	var document *tb.Document
+	for _, sent := range bot.AllLastSentWhat {
+		if d, isDocument := sent.(*tb.Document); isDocument {
+			document = d
+		}
+	}
+	if document == nil {
+		t.Fatalf("export should send a document: %v", bot.AllLastSentWhat)
+	}
+	helpers.TestStringContains(t, document.FileName, ".beancount", "file name")
+	data, err := io.ReadAll(document.FileReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	helpers.TestExpect(t, string(data), `2026-09-28 * "Rent"
+  Assets:Checking -800.00 EUR
+  Expenses:Rent
+
+2026-10-12 * "Lunch"
+  Assets:Cash -12.00 EUR
+  Expenses:Food
+`, "exported file")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Archived the 2 exported transactions", "archive result")
+
+	// Nothing to export
+	mock.ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id", "value", "created" FROM "bot::transaction"`)).
+		WithArgs(chat.ID, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "created"}))
+	bc.commandExport(&MockContext{M: &tb.Message{Chat: chat, Text: "/export archived"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "There are no transactions to export", "empty export")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,6 +1,8 @@
 package bot
 
 import (
+	"io"
+	"strings"
 	"time"
 
 	tb "gopkg.in/telebot.v3"
@@ -8,19 +10,26 @@ import (
 
 type MockBot struct {
 	LastSentWhat    interface{}
+	LastSentOptions []interface{}
 	AllLastSentWhat []interface{}
+
+	Files map[string]string // contents of files sent to the bot by file ID
 }
 
 func (b *MockBot) Start()                                                                       {}
 func (b *MockBot) Handle(endpoint interface{}, handler tb.HandlerFunc, mw ...tb.MiddlewareFunc) {}
 func (b *MockBot) Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error) {
 	b.LastSentWhat = what
+	b.LastSentOptions = options
 	b.AllLastSentWhat = append(b.AllLastSentWhat, what)
 	return nil, nil
 }
 func (b *MockBot) Respond(c *tb.Callback, resp ...*tb.CallbackResponse) error {
 	return nil
 }
+func (b *MockBot) File(file *tb.File) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(b.Files[file.FileID])), nil
+}
 func (b *MockBot) Me() *tb.User {
 	return &tb.User{Username: "Test bot"}
 }
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// After the last posting of a simple transaction, further postings can be added to split the amount.
+// The last posting is left without amount, so that it takes up the remainder.
+const (
+	POSTING_DONE = "Done"
+	POSTING_ADD  = "Add another posting"
+
+	POSTING_SPECIFIER_PREFIX = "posting"
+)
+
+func HandlePosting(m *tb.Message) (string, error) {
+	switch strings.TrimSpace(m.Text) {
+	case POSTING_DONE:
+		return "", nil
+	case POSTING_ADD:
+		return POSTING_ADD, nil
+	}
+	return "", fmt.Errorf("please select either '%s' or '%s'", POSTING_DONE, POSTING_ADD)
+}
+
+var additionalPostingPattern = regexp.MustCompile(`^` + POSTING_SPECIFIER_PREFIX + `\d+$`)
+
+// isAdditionalPosting checks whether the field belongs to a posting added with POSTING_ADD
+func isAdditionalPosting(f *TemplateField) bool {
+	return additionalPostingPattern.MatchString(f.FieldSpecifier)
+}
+
+func (tx *SimpleTx) additionalPostingsCount() int {
+	count := 0
+	for _, f := range ParseTemplateFields(tx.template, "") {
+		if f.FieldName == c.FIELD_ACCOUNT && isAdditionalPosting(f) {
+			count++
+		}
+	}
+	return count
+}
+
+// addPosting inserts a new posting line before the last posting (holding the posting field) and asks for its account and amount
+func (tx *SimpleTx) addPosting(postingField *TemplateField) {
+	specifier := POSTING_SPECIFIER_PREFIX + strconv.Itoa(tx.additionalPostingsCount()+1)
+	account := ParseTemplateField(fmt.Sprintf("%s:%s:of the *additional posting*", c.FIELD_ACCOUNT, specifier), tx.userCurrencySuggestion)
+	amount := ParseTemplateField(fmt.Sprintf("%s:%s:for the *additional posting*", c.FIELD_AMOUNT, specifier), tx.userCurrencySuggestion)
+
+	lines := strings.Split(tx.template, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "${"+postingField.Raw+"}") {
+			newLine := fmt.Sprintf("  ${%s} ${%s}", account.Raw, amount.Raw)
+			lines = append(lines[:i], append([]string{newLine}, lines[i:]...)...)
+			break
+		}
+	}
+	tx.template = strings.Join(lines, "\n")
+	tx.nextFields = append([]*TemplateField{account, amount}, tx.nextFields...)
+}
+
+// removeLastPosting undoes addPosting, as long as the added posting has not been answered yet
+func (tx *SimpleTx) removeLastPosting() {
+	specifier := POSTING_SPECIFIER_PREFIX + strconv.Itoa(tx.additionalPostingsCount())
+	lines := []string{}
+	for _, line := range strings.Split(tx.template, "\n") {
+		if !strings.Contains(line, fmt.Sprintf("${%s:%s:", c.FIELD_ACCOUNT, specifier)) {
+			lines = append(lines, line)
+		}
+	}
+	tx.template = strings.Join(lines, "\n")
+	nextFields := []*TemplateField{}
+	for _, f := range tx.nextFields {
+		if f.FieldSpecifier != specifier {
+			nextFields = append(nextFields, f)
+		}
+	}
+	tx.nextFields = nextFields
+}
+
+// postingsRemainder returns the part of the amount left for the last posting.
+// It can only be determined if the additional postings are given in the currency of the amount (and without price annotations).
+func (tx *SimpleTx) postingsRemainder() (remainder c.Decimal, currency string, ok bool) {
+	total, currency, annotation, err := splitAmount(strings.ReplaceAll(tx.data[c.FqCacheKey(c.FIELD_AMOUNT)], FORMATTER_PLACEHOLDER, ""))
+	if err != nil || annotation != nil {
+		return remainder, "", false
+	}
+	remainder = total
+	for key, value := range tx.data {
+		if c.TypeCacheKey(key) != c.FIELD_AMOUNT || !additionalPostingPattern.MatchString(strings.TrimPrefix(key, c.FIELD_AMOUNT+":")) {
+			continue
+		}
+		amount, postingCurrency, annotation, err := splitAmount(strings.ReplaceAll(value, FORMATTER_PLACEHOLDER, ""))
+		if err != nil || annotation != nil || postingCurrency != currency {
+			return remainder, "", false
+		}
+		remainder = remainder.Sub(amount)
+	}
+	if currency == "" {
+		currency = tx.userCurrencySuggestion
+	}
+	_, shares, _, err := tx.splitShares(currency)
+	if err != nil {
+		return remainder, "", false
+	}
+	for _, share := range shares {
+		remainder = remainder.Sub(share)
+	}
+	return remainder, currency, true
+}
+
+// validateAmounts checks that the shares of the participants and the additional postings leave some amount for the last posting
+func (tx *SimpleTx) validateAmounts() error {
+	if _, _, _, err := tx.splitShares(tx.userCurrencySuggestion); err != nil {
+		return err
+	}
+	if tx.additionalPostingsCount() == 0 {
+		return nil
+	}
+	if remainder, _, ok := tx.postingsRemainder(); ok && remainder.Sign() <= 0 {
+		return fmt.Errorf("the additional postings need to be less than the amount of the transaction, so that some amount remains for the last posting")
+	}
+	return nil
+}
+
+func (tx *SimpleTx) hintPosting(i *Input) *Hint {
+	i.hint.KeyboardOptions = []string{POSTING_DONE, POSTING_ADD}
+	if remainder, currency, ok := tx.postingsRemainder(); ok && tx.additionalPostingsCount() > 0 {
+		i.hint.Prompt += fmt.Sprintf("\n\nThe last posting takes up the remaining amount of %s %s.", escapeMarkdownValue(tx.locale.Format(ParseAmount(remainder, currency))), escapeMarkdownValue(currency))
+	}
+	return i.hint
+}
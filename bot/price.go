@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// Price is a beancount price directive, e.g. '2026-10-17 price VTI  210.55 USD'
+type Price struct {
+	Date      string
+	Commodity string
+	Amount    h.Decimal
+	Currency  string
+}
+
+// Pair is the commodity pair of the price, as remembered for suggestions
+func (p *Price) Pair() string {
+	return p.Commodity + " " + p.Currency
+}
+
+func (p *Price) String() string {
+	line := fmt.Sprintf("%s price %s %s%s %s", p.Date, p.Commodity, FORMATTER_PLACEHOLDER, ParseAmount(p.Amount, p.Currency), p.Currency)
+	return formatAllLinesWithFormatterPlaceholder(line, h.DOT_INDENT, p.Currency)
+}
+
+// ParsePrice parses the parameters '<commodity> <amount> [<currency>] [<date>]' of the price command.
+// If the currency is left out, the one used most recently for the commodity is taken from recentPairs.
+// The amount is read in the user's locale (or with guessed separators, if nil), as in transactions.
+func ParsePrice(params []string, recentPairs []string, tzOffset int, locale *h.Locale) (*Price, error) {
+	if len(params) < 2 {
+		return nil, fmt.Errorf("please provide at least the commodity and its price")
+	}
+	p := &Price{Commodity: params[0]}
+	if err := h.IsValidCommodity(p.Commodity); err != nil {
+		return nil, err
+	}
+	amount, err := evaluateExpression(params[1], locale)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("the price '%s' must be positive", params[1])
+	}
+	p.Amount = amount
+
+	dateParams := params[2:]
+	if len(dateParams) > 0 && h.IsValidCommodity(dateParams[0]) == nil {
+		p.Currency = dateParams[0]
+		dateParams = dateParams[1:]
+	} else {
+		for _, pair := range recentPairs {
+			if strings.HasPrefix(pair, p.Commodity+" ") {
+				p.Currency = strings.TrimPrefix(pair, p.Commodity+" ")
+				break
+			}
+		}
+		if p.Currency == "" {
+			return nil, fmt.Errorf("please provide the currency the price of '%s' is given in", p.Commodity)
+		}
+	}
+	if p.Currency == p.Commodity {
+		return nil, fmt.Errorf("the price of '%s' can't be given in the commodity itself", p.Commodity)
+	}
+
+	p.Date = Today(tzOffset).Format(h.BEANCOUNT_DATE_FORMAT)
+	if len(dateParams) > 0 {
+		p.Date, err = ParseDate(strings.Join(dateParams, " "), tzOffset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (bc *BotController) commandPrice(c tb.Context) error {
+	m := c.Message()
+	if bc.State.GetType(m) != ST_NONE {
+		bc.Logf(INFO, m, "commandPrice while in another transaction")
+		bc.Bot.SendSilent(bc, Recipient(m), MSG_UNFINISHED_STATE)
+		return nil
+	}
+	recentPairs, err := bc.Repo.GetCacheHints(m, h.FqCacheKey(h.FIELD_PRICE))
+	if err != nil {
+		bc.Logf(ERROR, m, "Could not get recently used commodity pairs: %s", err.Error())
+	}
+	params := commandParams(m.Text)
+	if len(params) == 0 {
+		bc.priceHelp(m, recentPairs, nil)
+		return nil
+	}
+	price, err := ParsePrice(params, recentPairs, bc.Repo.UserGetTzOffset(m), bc.Repo.UserGetLocale(m))
+	if err != nil {
+		bc.priceHelp(m, recentPairs, err)
+		return nil
+	}
+
+	err = bc.Repo.RecordTransaction(m.Chat.ID, price.String())
+	if err != nil {
+		bc.Logf(ERROR, m, "Something went wrong while recording the price: "+err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while recording your price: "+err.Error(), clearKeyboard())
+		return nil
+	}
+	err = bc.Repo.PutCacheHints(m, map[string]string{h.FIELD_PRICE: price.Pair()})
+	if err != nil {
+		bc.Logf(ERROR, m, "Something went wrong while caching the commodity pair. Error: %s", err.Error())
+		// Don't return, the price has been recorded
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully added the price of %s to your transaction /%s", price.Commodity, CMD_LIST), clearKeyboard())
+	return nil
+}
+
+func (bc *BotController) priceHelp(m *tb.Message, recentPairs []string, err error) {
+	errorMsg := ""
+	if err != nil {
+		errorMsg += fmt.Sprintf("Error executing your command: %s\n\n", err.Error())
+	}
+	recentMsg := ""
+	if len(recentPairs) > 0 {
+		recentMsg = "\n\nYour recently used commodity pairs:\n" + strings.Join(recentPairs, "\n")
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), errorMsg+`Usage help for /price:
+/price <commodity> <amount> [<currency>] [<date>]
+e.g. /price VTI 210.55 USD 2022-01-24
+
+The currency can be left out for commodities you recorded a price for before. The date defaults to today.`+recentMsg)
+}
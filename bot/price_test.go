@@ -0,0 +1,56 @@
+package bot_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func TestParsePrice(t *testing.T) {
+	price, err := bot.ParsePrice([]string{"VTI", "210.5", "USD", "2022-04-11"}, nil, 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing price should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, price.Pair(), "VTI USD", "")
+	helpers.TestExpect(t, price.String(), "2022-04-11 price VTI                          210.50 USD\n", "")
+
+	price, err = bot.ParsePrice([]string{"VTI", "1,234.5678"}, []string{"EUR USD", "VTI USD", "VTI EUR"}, 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing price without currency should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, price.Currency, "USD", "most recently used currency for the commodity should be taken")
+	helpers.TestExpect(t, price.Amount.Format(2), "1234.5678", "precision of the price should be kept")
+	helpers.TestExpect(t, price.Date, time.Now().UTC().Format(helpers.BEANCOUNT_DATE_FORMAT), "date should default to today")
+
+	price, err = bot.ParsePrice([]string{"VTI", "210.55", "yesterday"}, []string{"VTI USD"}, 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing price with date but without currency should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, price.Date, time.Now().UTC().Add(-24*time.Hour).Format(helpers.BEANCOUNT_DATE_FORMAT), "")
+
+	de, _ := helpers.GetLocale("de")
+	for input, expected := range map[string]string{"1,5": "1.50", "1.234,50": "1234.50"} {
+		price, err = bot.ParsePrice([]string{"VTI", input, "EUR"}, nil, 0, de)
+		if err != nil {
+			t.Fatalf("Parsing price in locale 'de' should work: %s", err.Error())
+		}
+		helpers.TestExpect(t, price.Amount.Format(2), expected, "price should be read in the user's locale")
+	}
+
+	for _, invalid := range [][]string{
+		{"VTI"},
+		{"vti", "210.55", "USD"},
+		{"VTI", "abc", "USD"},
+		{"VTI", "-210.55", "USD"},
+		{"VTI", "210.55", "usd"},
+		{"VTI", "210.55", "VTI"},
+		{"VTI", "210.55"},
+		{"VTI", "210.55", "USD", "notADate"},
+	} {
+		if _, err := bot.ParsePrice(invalid, nil, 0, nil); err == nil {
+			t.Errorf("Expected error parsing price %v", invalid)
+		}
+	}
+}
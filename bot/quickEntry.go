@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// Quick entries record a transaction from a single message:
+//   <amount> [<CURRENCY>] [<description>] [> <to account>] [< <from account>] [#<tag>] [<date>]
+// e.g. '12.50 Coffee shop > Expenses:Food:Coffee < Assets:Cash #trip 2026-10-15'.
+// The date is only recognized after an account or tag. All parts but the amount are optional.
+// The currency is only recognized directly after the amount, so that all-caps words within the description (e.g. 'Tickets BUS 42') are kept.
+
+const (
+	QUICK_ENTRY_TO   = ">"
+	QUICK_ENTRY_FROM = "<"
+	QUICK_ENTRY_TAG  = "#"
+)
+
+type QuickEntry struct {
+	Amount      string
+	Description string
+	To          string
+	From        string
+	Tag         string
+	Date        string
+}
+
+func isQuickEntryMarker(token string) bool {
+	return strings.HasPrefix(token, QUICK_ENTRY_TO) || strings.HasPrefix(token, QUICK_ENTRY_FROM) || strings.HasPrefix(token, QUICK_ENTRY_TAG)
+}
+
+// IsQuickEntry checks whether a message starts with an amount, followed by further parts of a quick entry.
+// The amount is read in the locale, as when parsing the quick entry.
+func IsQuickEntry(text string, locale *c.Locale) bool {
+	tokens := strings.Fields(text)
+	if len(tokens) < 2 {
+		return false
+	}
+	_, err := handleAmount(&tb.Message{Text: tokens[0]}, false, locale)
+	return err == nil
+}
+
+func ParseQuickEntry(text string, tzOffset int, locale *c.Locale) (*QuickEntry, error) {
+	tokens := strings.Fields(text)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("the quick entry is empty")
+	}
+	entry := &QuickEntry{}
+
+	amountInput := tokens[0]
+	i := 1
+	if len(tokens) > 1 && c.IsValidCommodity(tokens[1]) == nil {
+		amountInput += " " + tokens[1]
+		i++
+	}
+	amount, err := handleAmount(&tb.Message{Text: amountInput}, false, locale)
+	if err != nil {
+		return nil, err
+	}
+	entry.Amount = amount
+
+	descriptionWords := []string{}
+	for ; i < len(tokens) && !isQuickEntryMarker(tokens[i]); i++ {
+		descriptionWords = append(descriptionWords, tokens[i])
+	}
+	entry.Description = strings.Join(descriptionWords, " ")
+
+	dateWords := []string{}
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		var target *string
+		var marker string
+		switch {
+		case strings.HasPrefix(token, QUICK_ENTRY_TO):
+			target, marker = &entry.To, QUICK_ENTRY_TO
+		case strings.HasPrefix(token, QUICK_ENTRY_FROM):
+			target, marker = &entry.From, QUICK_ENTRY_FROM
+		case strings.HasPrefix(token, QUICK_ENTRY_TAG):
+			target, marker = &entry.Tag, QUICK_ENTRY_TAG
+		default:
+			dateWords = append(dateWords, token)
+			continue
+		}
+		if len(dateWords) > 0 {
+			return nil, fmt.Errorf("unexpected '%s' before '%s'. The date has to be given last", strings.Join(dateWords, " "), token)
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("'%s' has been given more than once", marker)
+		}
+		value := strings.TrimPrefix(token, marker)
+		if value == "" && marker != QUICK_ENTRY_TAG && i+1 < len(tokens) && !isQuickEntryMarker(tokens[i+1]) {
+			// Separated by space, e.g. '> Expenses:Food'
+			i++
+			value = tokens[i]
+		}
+		if value == "" {
+			return nil, fmt.Errorf("'%s' has to be followed by a value", marker)
+		}
+		if marker != QUICK_ENTRY_TAG {
+			if err := c.IsValidAccount(value); err != nil {
+				return nil, err
+			}
+		}
+		*target = value
+	}
+	if len(dateWords) > 0 {
+		entry.Date, err = ParseDate(strings.Join(dateWords, " "), tzOffset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// Data returns the given parts of the quick entry as data for TEMPLATE_SIMPLE_DEFAULT
+func (e *QuickEntry) Data() map[string]string {
+	data := map[string]string{
+		c.FqCacheKey(c.FIELD_AMOUNT): e.Amount,
+	}
+	if e.Description != "" {
+		data[c.FqCacheKey(c.FIELD_DESCRIPTION)] = e.Description
+	}
+	if e.From != "" {
+		data[c.FqCacheKey(c.FIELD_ACCOUNT+":"+c.FIELD_ACCOUNT_FROM)] = e.From
+	}
+	if e.To != "" {
+		data[c.FqCacheKey(c.FIELD_ACCOUNT+":"+c.FIELD_ACCOUNT_TO)] = e.To
+	}
+	if e.Tag != "" {
+		data[c.FqCacheKey(c.FIELD_TAG)] = " " + QUICK_ENTRY_TAG + e.Tag
+	}
+	if e.Date != "" {
+		data[c.FqCacheKey(c.FIELD_DATE)] = e.Date
+	}
+	return data
+}
@@ -0,0 +1,85 @@
+package bot_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func TestIsQuickEntry(t *testing.T) {
+	helpers.TestExpect(t, bot.IsQuickEntry("12.50 Coffee", nil), true, "")
+	helpers.TestExpect(t, bot.IsQuickEntry("12.50 EUR", nil), true, "")
+	helpers.TestExpect(t, bot.IsQuickEntry("12.50", nil), false, "a plain amount is handled on its own")
+	helpers.TestExpect(t, bot.IsQuickEntry("Coffee 12.50", nil), false, "")
+
+	de, _ := helpers.GetLocale("de")
+	helpers.TestExpect(t, bot.IsQuickEntry("12,50 Coffee", de), true, "amount should be read in the locale")
+	helpers.TestExpect(t, bot.IsQuickEntry("1,234.50 Coffee", de), false, "amount not valid in the locale")
+}
+
+func TestParseQuickEntry(t *testing.T) {
+	entry, err := bot.ParseQuickEntry("12.50 Coffee shop > Expenses:Food:Coffee < Assets:Cash #trip 2026-10-15", 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing quick entry should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, *entry, bot.QuickEntry{
+		Amount:      bot.FORMATTER_PLACEHOLDER + "12.50",
+		Description: "Coffee shop",
+		To:          "Expenses:Food:Coffee",
+		From:        "Assets:Cash",
+		Tag:         "trip",
+		Date:        "2026-10-15",
+	}, "")
+	data := entry.Data()
+	helpers.TestExpect(t, data["account:to"], "Expenses:Food:Coffee", "")
+	helpers.TestExpect(t, data["tag:"], " #trip", "")
+
+	entry, err = bot.ParseQuickEntry("3*4 USD Lunch with friends <Assets:Cash >Expenses:Food last friday", 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing quick entry should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, entry.Amount, bot.FORMATTER_PLACEHOLDER+"12.00 USD", "")
+	helpers.TestExpect(t, entry.Description, "Lunch with friends", "")
+	helpers.TestExpect(t, entry.From, "Assets:Cash", "")
+	helpers.TestExpect(t, entry.To, "Expenses:Food", "")
+	if entry.Date == "" {
+		t.Errorf("Relative date should have been parsed")
+	}
+
+	entry, err = bot.ParseQuickEntry("5 Snacks yesterday", 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing quick entry should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, entry.Description, "Snacks yesterday", "date is only recognized after an account or tag")
+	helpers.TestExpect(t, len(entry.Data()), 2, "only amount and description should be given")
+
+	entry, err = bot.ParseQuickEntry("12.50 GBP Lunch", 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing quick entry should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, entry.Amount, bot.FORMATTER_PLACEHOLDER+"12.50 GBP", "any valid commodity after the amount should be taken as currency")
+	helpers.TestExpect(t, entry.Description, "Lunch", "")
+
+	entry, err = bot.ParseQuickEntry("3 Tickets BUS 42 > Expenses:Transport", 0, nil)
+	if err != nil {
+		t.Fatalf("Parsing quick entry should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, entry.Amount, bot.FORMATTER_PLACEHOLDER+"3.00", "")
+	helpers.TestExpect(t, entry.Description, "Tickets BUS 42", "all-caps words within the description should be kept")
+
+	for _, invalid := range []string{
+		"abc Coffee",
+		"5 Coffee >",
+		"5 Coffee > A:B > A:C",
+		"5 Coffee > A:B notADate",
+		"5 Coffee > A:B 2026-10-15 < A:C",
+		"5 Coffee # tag",
+		"5 Coffee > expenses:food",
+		"5 Coffee < Cash",
+	} {
+		if _, err := bot.ParseQuickEntry(invalid, 0, nil); err == nil {
+			t.Errorf("Expected error parsing quick entry '%s'", invalid)
+		}
+	}
+}
@@ -16,3 +16,21 @@ func ReplyKeyboard(buttons []string) *tb.ReplyMarkup {
 	kb.Reply(buttonsCreated...)
 	return kb
 }
+
+// ReplyKeyboardRows shows the buttons in the given rows, e.g. to group them
+func ReplyKeyboardRows(rows [][]string) *tb.ReplyMarkup {
+	if len(rows) == 0 {
+		return clearKeyboard()
+	}
+	kb := &tb.ReplyMarkup{ResizeKeyboard: true, OneTimeKeyboard: true}
+	rowsCreated := []tb.Row{}
+	for _, row := range rows {
+		buttons := []tb.Btn{}
+		for _, label := range row {
+			buttons = append(buttons, kb.Text(label))
+		}
+		rowsCreated = append(rowsCreated, kb.Row(buttons...))
+	}
+	kb.Reply(rowsCreated...)
+	return kb
+}
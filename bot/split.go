@@ -0,0 +1,339 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// Shared expenses are booked as receivables per participant, e.g. 'Assets:Receivable:Alice'.
+// Your own share remains on the last posting.
+const RECEIVABLE_ACCOUNT_PREFIX = "Assets:Receivable:"
+
+const TEMPLATE_SPLIT = `${date} ${flag} "${description}"${tag}
+  ${account:from:the money came *from*} ${-amount}
+${split}
+  ${account:to:your own share went *to*}${posting}`
+
+type ShareType string
+
+const (
+	SHARE_EQUAL      ShareType = "equal"
+	SHARE_PERCENTAGE ShareType = "percentage"
+	SHARE_EXACT      ShareType = "exact"
+)
+
+// Share is the part of a split amount a participant owes, e.g. 'Alice', 'Bob=40%' or 'Carol=12.50'
+type Share struct {
+	Participant string
+	Type        ShareType
+	Value       h.Decimal
+}
+
+func (s *Share) Account() string {
+	return RECEIVABLE_ACCOUNT_PREFIX + s.Participant
+}
+
+func (s *Share) String() string {
+	switch s.Type {
+	case SHARE_PERCENTAGE:
+		return fmt.Sprintf("%s=%s%%", s.Participant, s.Value.String())
+	case SHARE_EXACT:
+		return fmt.Sprintf("%s=%s", s.Participant, s.Value.String())
+	}
+	return s.Participant
+}
+
+func ParseShare(s string) (*Share, error) {
+	share := &Share{Type: SHARE_EQUAL}
+	parts := strings.SplitN(s, "=", 2)
+	participant := parts[0]
+	share.Participant = participant
+	if err := h.IsValidAccount(share.Account()); err != nil {
+		return nil, fmt.Errorf("the participant '%s' can't be used in an account name: %s", participant, err.Error())
+	}
+	if len(parts) < 2 {
+		return share, nil
+	}
+	value := parts[1]
+	share.Type = SHARE_EXACT
+	if strings.HasSuffix(value, "%") {
+		share.Type = SHARE_PERCENTAGE
+		value = strings.TrimSuffix(value, "%")
+	}
+	var err error
+	share.Value, err = h.ParseDecimal(value)
+	if err != nil {
+		return nil, fmt.Errorf("the share '%s' of '%s' is not a valid number", value, participant)
+	}
+	if share.Value.Sign() <= 0 {
+		return nil, fmt.Errorf("the share of '%s' must be positive", participant)
+	}
+	if share.Type == SHARE_PERCENTAGE && share.Value.Cmp(h.NewDecimal(100)) > 0 {
+		return nil, fmt.Errorf("the share of '%s' can't exceed 100%%", participant)
+	}
+	return share, nil
+}
+
+// ParseShares parses the participants of a split, e.g. ['Alice', 'Bob=40%', 'Carol=12.50']
+func ParseShares(params []string) ([]*Share, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("please provide at least one participant")
+	}
+	shares := []*Share{}
+	seen := map[string]bool{}
+	percentages := h.NewDecimal(0)
+	for _, param := range params {
+		share, err := ParseShare(param)
+		if err != nil {
+			return nil, err
+		}
+		if seen[share.Participant] {
+			return nil, fmt.Errorf("the participant '%s' has been given more than once", share.Participant)
+		}
+		seen[share.Participant] = true
+		if share.Type == SHARE_PERCENTAGE {
+			percentages = percentages.Add(share.Value)
+		}
+		shares = append(shares, share)
+	}
+	if percentages.Cmp(h.NewDecimal(100)) > 0 {
+		return nil, fmt.Errorf("the percentages of all participants add up to more than 100%%")
+	}
+	return shares, nil
+}
+
+func SharesString(shares []*Share) string {
+	s := []string{}
+	for _, share := range shares {
+		s = append(s, share.String())
+	}
+	return strings.Join(s, " ")
+}
+
+// ShareAmounts calculates the amount each participant owes of the total, rounded to the given precision.
+// Equal shares split what is left after exact amounts and percentages between the participants and yourself.
+func ShareAmounts(shares []*Share, total h.Decimal, precision int) ([]h.Decimal, error) {
+	if total.Sign() <= 0 {
+		return nil, fmt.Errorf("only positive amounts can be split")
+	}
+	amounts := make([]h.Decimal, len(shares))
+	rest := total
+	equalShares := 0
+	for i, share := range shares {
+		switch share.Type {
+		case SHARE_EXACT:
+			amounts[i] = share.Value
+		case SHARE_PERCENTAGE:
+			amounts[i] = total.Mul(share.Value).Quo(h.NewDecimal(100)).Round(precision)
+		default:
+			equalShares++
+			continue
+		}
+		rest = rest.Sub(amounts[i])
+	}
+	if rest.Sign() < 0 {
+		return nil, fmt.Errorf("the shares of the participants exceed the amount of %s", ParseAmount(total, ""))
+	}
+	// Your own share takes up the rounding remainder
+	equalAmount := rest.Quo(h.NewDecimal(int64(equalShares + 1))).Round(precision)
+	for i, share := range shares {
+		if share.Type == SHARE_EQUAL {
+			amounts[i] = equalAmount
+		}
+	}
+	return amounts, nil
+}
+
+// splitShares returns the shares of the participants the transaction is split between, if any
+func (tx *SimpleTx) splitShares(defaultCurrency string) (shares []*Share, amounts []h.Decimal, currency string, err error) {
+	participants, isSplit := tx.data[h.FqCacheKey(h.FIELD_SPLIT)]
+	amountValue, hasAmount := tx.data[h.FqCacheKey(h.FIELD_AMOUNT)]
+	if !isSplit || !hasAmount {
+		return nil, nil, "", nil
+	}
+	shares, err = ParseShares(strings.Fields(participants))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	total, currency, annotation, err := splitAmount(strings.ReplaceAll(amountValue, FORMATTER_PLACEHOLDER, ""))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if annotation != nil {
+		return nil, nil, "", fmt.Errorf("amounts with price annotations can't be split between participants")
+	}
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	amounts, err = ShareAmounts(shares, total, h.CurrencyPrecision(currency))
+	return shares, amounts, currency, err
+}
+
+var splitFieldPattern = regexp.MustCompile(`\$\{` + h.FIELD_SPLIT + `\}`)
+
+// renderSplit replaces the line holding the split field with the receivable postings of the participants
+func (tx *SimpleTx) renderSplit(template, defaultCurrency string) (string, error) {
+	shares, amounts, currency, err := tx.splitShares(defaultCurrency)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(template, "\n") {
+		if !splitFieldPattern.MatchString(line) {
+			lines = append(lines, line)
+			continue
+		}
+		for i, share := range shares {
+			lines = append(lines, fmt.Sprintf("  %s %s%s %s", share.Account(), FORMATTER_PLACEHOLDER, ParseAmount(amounts[i], currency), currency))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (bc *BotController) commandCreateSplitTx(c tb.Context) error {
+	m := c.Message()
+	if bc.State.GetType(m) != ST_NONE {
+		bc.Bot.SendSilent(bc, Recipient(m), MSG_UNFINISHED_STATE)
+		return nil
+	}
+	shares, err := ParseShares(commandParams(m.Text))
+	if err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Error executing your command: %s\n\n", err.Error())+`Usage help for /split:
+/split <participant>[=<share>] ...
+e.g. /split Alice Bob=40% Carol=12.50
+
+Participants without share split the amount equally with you (after exact amounts and percentages). The shares are recorded as receivables, e.g. 'Assets:Receivable:Alice'. Check the open balances using /`+CMD_SETTLE+`.`, clearKeyboard())
+		return nil
+	}
+	bc.Logf(TRACE, m, "Creating split transaction")
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("In the following steps we will create a transaction split between you and %s. I will guide you through.\n\n", SharesString(shares)),
+		clearKeyboard(),
+	)
+	tx := bc.State.SplitTx(m, bc.Repo.UserGetCurrency(m), shares)
+	bc.applyUserPreferences(m, tx)
+	hint := tx.NextHint(bc.Repo, m)
+	bc.sendNextTxHint(hint, m)
+	return nil
+}
+
+// ReceivableBalances sums up the receivable postings per participant and currency.
+// Postings without amount are taken into account if their amount can be inferred from the other postings.
+func ReceivableBalances(transactions []string) map[string]map[string]h.Decimal {
+	balances := map[string]map[string]h.Decimal{}
+	for _, tx := range transactions {
+		for _, p := range parsePostings(tx) {
+			if !strings.HasPrefix(p.account, RECEIVABLE_ACCOUNT_PREFIX) || !p.hasAmount {
+				continue
+			}
+			participant := strings.TrimPrefix(p.account, RECEIVABLE_ACCOUNT_PREFIX)
+			if balances[participant] == nil {
+				balances[participant] = map[string]h.Decimal{}
+			}
+			balances[participant][p.currency] = balances[participant][p.currency].Add(p.amount)
+		}
+	}
+	return balances
+}
+
+type posting struct {
+	account   string
+	amount    h.Decimal
+	currency  string
+	hasAmount bool
+	annotated bool
+}
+
+func parsePostings(tx string) []*posting {
+	postings := []*posting{}
+	var missingAmount *posting
+	for _, line := range strings.Split(tx, "\n") {
+		fields := strings.Fields(line)
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") || len(fields) == 0 || strings.HasSuffix(fields[0], ":") {
+			// Transaction header, metadata or empty line
+			continue
+		}
+		p := &posting{account: fields[0]}
+		if len(fields) >= 3 {
+			amount, err := h.ParseDecimal(fields[1])
+			if err != nil {
+				continue
+			}
+			p.amount, p.currency, p.hasAmount, p.annotated = amount, fields[2], true, len(fields) > 3
+		} else if missingAmount == nil {
+			missingAmount = p
+		} else {
+			// The amounts of several postings can't be inferred
+			missingAmount = &posting{}
+		}
+		postings = append(postings, p)
+	}
+	if missingAmount == nil || missingAmount.account == "" {
+		return postings
+	}
+	sum := h.NewDecimal(0)
+	currency := ""
+	for _, p := range postings {
+		if p == missingAmount {
+			continue
+		}
+		if p.annotated || currency != "" && p.currency != currency {
+			return postings
+		}
+		currency = p.currency
+		sum = sum.Add(p.amount)
+	}
+	missingAmount.amount, missingAmount.currency, missingAmount.hasAmount = sum.Neg(), currency, currency != ""
+	return postings
+}
+
+func (bc *BotController) commandSettle(c tb.Context) error {
+	m := c.Message()
+	transactions := []string{}
+	for _, isArchived := range []bool{false, true} {
+		results, err := bc.Repo.GetTransactions(m, isArchived)
+		if err != nil {
+			bc.Logf(ERROR, m, "Something went wrong while getting transactions: %s", err.Error())
+			bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while reading your transactions: "+err.Error(), clearKeyboard())
+			return nil
+		}
+		for _, r := range results {
+			transactions = append(transactions, r.Tx)
+		}
+	}
+	balances := ReceivableBalances(transactions)
+	locale := bc.Repo.UserGetLocale(m)
+	participants := []string{}
+	for participant := range balances {
+		participants = append(participants, participant)
+	}
+	sort.Strings(participants)
+	lines := []string{}
+	for _, participant := range participants {
+		currencies := []string{}
+		for currency := range balances[participant] {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		for _, currency := range currencies {
+			balance := balances[participant][currency]
+			if balance.Sign() > 0 {
+				lines = append(lines, fmt.Sprintf("%s owes you %s %s", participant, locale.Format(ParseAmount(balance, currency)), currency))
+			} else if balance.Sign() < 0 {
+				lines = append(lines, fmt.Sprintf("You owe %s %s %s", participant, locale.Format(ParseAmount(balance.Neg(), currency)), currency))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), "There are no open balances with any participants of your split transactions.", clearKeyboard())
+		return nil
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), "Open balances of the participants in your recorded transactions:\n\n"+strings.Join(lines, "\n")+
+		"\n\nTo settle up, record the payment from or to the participant's receivable account, "+
+		"e.g. '10 Settle up > Assets:Cash < "+RECEIVABLE_ACCOUNT_PREFIX+"Alice'.", clearKeyboard())
+	return nil
+}
@@ -0,0 +1,105 @@
+package bot_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestParseShares(t *testing.T) {
+	shares, err := bot.ParseShares([]string{"Alice", "Bob=40%", "Carol=12.50"})
+	if err != nil {
+		t.Fatalf("Parsing shares should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, len(shares), 3, "")
+	helpers.TestExpect(t, shares[0].Type, bot.SHARE_EQUAL, "")
+	helpers.TestExpect(t, shares[1].Type, bot.SHARE_PERCENTAGE, "")
+	helpers.TestExpect(t, shares[2].Type, bot.SHARE_EXACT, "")
+	helpers.TestExpect(t, shares[2].Account(), "Assets:Receivable:Carol", "")
+	helpers.TestExpect(t, bot.SharesString(shares), "Alice Bob=40% Carol=12.5", "")
+
+	for _, invalid := range [][]string{
+		{},
+		{"alice"},
+		{"Alice", "Alice=5"},
+		{"Alice=abc"},
+		{"Alice=-5"},
+		{"Alice=60%", "Bob=50%"},
+	} {
+		_, err := bot.ParseShares(invalid)
+		if err == nil {
+			t.Errorf("Parsing shares should fail for %v", invalid)
+		}
+	}
+}
+
+func TestShareAmounts(t *testing.T) {
+	shares, _ := bot.ParseShares([]string{"Alice", "Bob=40%", "Carol=12.50"})
+	amounts, err := bot.ShareAmounts(shares, helpers.NewDecimal(100), 2)
+	if err != nil {
+		t.Fatalf("Calculating shares should work: %s", err.Error())
+	}
+	helpers.TestExpect(t, amounts[0].Format(2), "23.75", "rest should be split equally with yourself")
+	helpers.TestExpect(t, amounts[1].Format(2), "40.00", "")
+	helpers.TestExpect(t, amounts[2].Format(2), "12.50", "")
+
+	_, err = bot.ShareAmounts(shares, helpers.NewDecimal(20), 2)
+	if err == nil {
+		t.Errorf("Shares exceeding the amount should be rejected")
+	}
+}
+
+func TestSplitTransaction(t *testing.T) {
+	shares, _ := bot.ParseShares([]string{"Alice", "Bob"})
+	tx := bot.NewStateHandler().SplitTx(&tb.Message{Chat: &tb.Chat{ID: 12345}}, "EUR", shares)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"10", "Pizza", "Assets:Wallet", "Expenses:Food", bot.POSTING_DONE} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Fatalf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Pizza"
+  Assets:Wallet                               -10.00 EUR
+  Assets:Receivable:Alice                       3.33 EUR
+  Assets:Receivable:Bob                         3.33 EUR
+  Expenses:Food
+`, "participants should owe their shares, your own share takes up the remainder")
+
+	shares, _ = bot.ParseShares([]string{"Alice=20"})
+	tx = bot.NewStateHandler().SplitTx(&tb.Message{Chat: &tb.Chat{ID: 12345}}, "EUR", shares)
+	_, err = tx.Input(&tb.Message{Text: "10"})
+	if err == nil {
+		t.Errorf("Amounts smaller than the shares should be rejected")
+	}
+}
+
+func TestReceivableBalances(t *testing.T) {
+	balances := bot.ReceivableBalances([]string{
+		`2022-04-11 * "Pizza"
+  Assets:Wallet                               -10.00 EUR
+  Assets:Receivable:Alice                       3.33 EUR
+  Assets:Receivable:Bob                         3.33 EUR
+  Expenses:Food
+`,
+		`2022-04-12 * "Settle up"
+  Assets:Receivable:Alice                      -3.33 EUR
+  Assets:Cash
+`,
+		`2022-04-12 * "Cinema"
+  Assets:Receivable:Bob                        -12.00 EUR
+  Expenses:Fun
+`,
+		`2022-04-13 * "Paid back"
+  Assets:Cash                                 -20.00 USD
+  Assets:Receivable:Bob
+`,
+		"; some comment",
+	})
+	helpers.TestExpect(t, balances["Alice"]["EUR"].IsZero(), true, "settled balance should be zero")
+	helpers.TestExpect(t, balances["Bob"]["EUR"].Format(2), "-8.67", "")
+	helpers.TestExpect(t, balances["Bob"]["USD"].Format(2), "20.00", "amount of the posting without amount should be inferred")
+}
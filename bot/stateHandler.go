@@ -3,6 +3,7 @@ package bot
 import (
 	"strings"
 
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
 )
 
@@ -20,6 +21,9 @@ type StateHandler struct {
 	states    map[chatId]StateType
 	txStates  map[chatId]Tx
 	tplStates map[chatId]TemplateName
+	tplEdits  map[chatId]bool
+
+	accountConfirmations map[chatId]string
 }
 
 func NewStateHandler() *StateHandler {
@@ -27,11 +31,27 @@ func NewStateHandler() *StateHandler {
 		states:    map[chatId]StateType{},
 		txStates:  map[chatId]Tx{},
 		tplStates: map[chatId]TemplateName{},
+		tplEdits:  map[chatId]bool{},
+
+		accountConfirmations: map[chatId]string{},
 	}
 }
 
 func (s *StateHandler) Clear(m *tb.Message) {
 	delete(s.states, (chatId)(m.Chat.ID))
+	delete(s.tplEdits, (chatId)(m.Chat.ID))
+	delete(s.accountConfirmations, (chatId)(m.Chat.ID))
+}
+
+// ConfirmAccount returns whether the account has been sent for confirmation before.
+// Otherwise it is remembered for the next confirmation attempt.
+func (s *StateHandler) ConfirmAccount(m *tb.Message, account string) bool {
+	if s.accountConfirmations[(chatId)(m.Chat.ID)] == account {
+		delete(s.accountConfirmations, (chatId)(m.Chat.ID))
+		return true
+	}
+	s.accountConfirmations[(chatId)(m.Chat.ID)] = account
+	return false
 }
 
 func (s *StateHandler) GetType(m *tb.Message) StateType {
@@ -48,26 +68,75 @@ func (s *StateHandler) GetTx(m *tb.Message) Tx {
 	return nil
 }
 
-func (s *StateHandler) SimpleTx(m *tb.Message, suggestedCur string) (Tx, error) {
-	tx, err := CreateSimpleTx(suggestedCur, TEMPLATE_SIMPLE_DEFAULT)
+func (s *StateHandler) SimpleTx(m *tb.Message, suggestedCur string, tzOffset int) (Tx, error) {
+	params := commandParams(m.Text)
+	data := map[string]string{}
+	if len(params) > 0 && params[0] == FLAG_PENDING {
+		data[c.FqCacheKey(c.FIELD_FLAG)] = FLAG_PENDING
+		params = params[1:]
+	}
+	tx := createSimpleTxWithData(suggestedCur, TEMPLATE_SIMPLE_DEFAULT, data)
+	err := setDateFromParams(params, tx, tzOffset)
 	if err != nil {
 		return nil, err
 	}
-	// Set date:
-	command := strings.Split(m.Text, " ")
-	if len(command) >= 2 {
-		date, err := ParseDate(command[1])
-		if err != nil {
-			return nil, err
-		}
-		tx.SetDate(date)
+	s.states[(chatId)(m.Chat.ID)] = ST_TX
+	s.txStates[(chatId)(m.Chat.ID)] = tx
+	return tx, nil
+}
+
+func (s *StateHandler) BalanceTx(m *tb.Message, suggestedCur string, tzOffset int) (Tx, error) {
+	tx, err := CreateBalanceTx(suggestedCur)
+	if err != nil {
+		return nil, err
+	}
+	err = setDateFromParams(commandParams(m.Text), tx, tzOffset)
+	if err != nil {
+		return nil, err
 	}
 	s.states[(chatId)(m.Chat.ID)] = ST_TX
 	s.txStates[(chatId)(m.Chat.ID)] = tx
 	return tx, nil
 }
 
-func (s *StateHandler) TemplateTx(m *tb.Message, template, suggestedCur, date string) (Tx, error) {
+// commandParams returns the parameters following the command, e.g. ['!', 'yesterday'] for '/simple ! yesterday'
+func commandParams(text string) []string {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields[1:]
+}
+
+// setDateFromParams sets the date given as command parameters, e.g. '/simple yesterday'
+func setDateFromParams(params []string, tx Tx, tzOffset int) error {
+	if len(params) == 0 {
+		return nil
+	}
+	_, err := tx.SetDate(strings.Join(params, " "), tzOffset)
+	return err
+}
+
+// SplitTx creates a simple transaction, whose amount is split between you and the participants
+func (s *StateHandler) SplitTx(m *tb.Message, suggestedCur string, shares []*Share) Tx {
+	data := map[string]string{c.FqCacheKey(c.FIELD_SPLIT): SharesString(shares)}
+	tx := createSimpleTxWithData(suggestedCur, TEMPLATE_SPLIT, data)
+	s.states[(chatId)(m.Chat.ID)] = ST_TX
+	s.txStates[(chatId)(m.Chat.ID)] = tx
+	return tx
+}
+
+// QuickTx creates a simple transaction, prefilled with the data given in a quick entry
+func (s *StateHandler) QuickTx(m *tb.Message, suggestedCur string, data map[string]string) Tx {
+	// Quick entries are recorded with two postings only
+	data[c.FqCacheKey(c.FIELD_POSTING)] = ""
+	tx := createSimpleTxWithData(suggestedCur, TEMPLATE_SIMPLE_DEFAULT, data)
+	s.states[(chatId)(m.Chat.ID)] = ST_TX
+	s.txStates[(chatId)(m.Chat.ID)] = tx
+	return tx
+}
+
+func (s *StateHandler) TemplateTx(m *tb.Message, template, suggestedCur, date string, tzOffset int) (Tx, error) {
 	tx, err := CreateSimpleTx(suggestedCur, template)
 	if err != nil {
 		return nil, err
@@ -77,18 +146,35 @@ func (s *StateHandler) TemplateTx(m *tb.Message, template, suggestedCur, date st
 
 	// set date
 	if date != "" {
-		date, err := ParseDate(date)
-		if err != nil {
-			return nil, err
-		}
-		return tx.SetDate(date)
+		return tx.SetDate(date, tzOffset)
 	}
 	return tx, nil
 }
 
+func (s *StateHandler) EditTx(m *tb.Message, serialized, suggestedCur string, tzOffset int, elementId int, isArchived bool) (Tx, error) {
+	tx, err := CreateEditTx(serialized, suggestedCur, tzOffset, elementId, isArchived)
+	if err != nil {
+		return nil, err
+	}
+	s.states[(chatId)(m.Chat.ID)] = ST_TX
+	s.txStates[(chatId)(m.Chat.ID)] = tx
+	return tx, nil
+}
+
 func (s *StateHandler) StartTpl(m *tb.Message, name string) {
 	s.states[(chatId)(m.Chat.ID)] = ST_TPL
 	s.tplStates[(chatId)(m.Chat.ID)] = TemplateName(name)
+	delete(s.tplEdits, (chatId)(m.Chat.ID))
+}
+
+// StartTplEdit waits for the new version of an existing template
+func (s *StateHandler) StartTplEdit(m *tb.Message, name string) {
+	s.StartTpl(m, name)
+	s.tplEdits[(chatId)(m.Chat.ID)] = true
+}
+
+func (s *StateHandler) IsTplEdit(m *tb.Message) bool {
+	return s.tplEdits[(chatId)(m.Chat.ID)]
 }
 
 func (s *StateHandler) CountOpen() int {
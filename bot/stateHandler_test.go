@@ -11,7 +11,7 @@ func TestStateClearing(t *testing.T) {
 	message := &tb.Message{Chat: &tb.Chat{ID: 24}}
 	stateHandler := bot.NewStateHandler()
 
-	stateHandler.SimpleTx(message, "")
+	stateHandler.SimpleTx(message, "", 0)
 	state := stateHandler.GetTx(message)
 	if state == nil {
 		t.Errorf("State from StateHandler before clearing was wrong, got: nil, want: not nil.")
@@ -11,7 +11,7 @@ import (
 func isAllowedSuggestionType(s string) bool {
 	splits := strings.SplitN(s, ":", 2)
 	_, exists := TEMPLATE_TYPE_HINTS[Type(splits[0])]
-	return exists
+	return exists || splits[0] == h.FIELD_CURRENCY
 }
 
 func (bc *BotController) suggestionsHandler(m *tb.Message) {
@@ -28,10 +28,13 @@ func (bc *BotController) suggestionsHandler(m *tb.Message) {
 
 func (bc *BotController) suggestionsHelp(m *tb.Message, err error) {
 	suggestionTypes := []string{}
-	for _, suggType := range h.AllowedSuggestionTypes() {
+	for _, suggType := range append(h.AllowedSuggestionTypes(), h.FIELD_CURRENCY) {
 		if suggType == h.FIELD_ACCOUNT {
 			suggType += ":[from,to,...]"
 		}
+		if suggType == h.FIELD_META {
+			suggType += ":<key>"
+		}
 		suggestionTypes = append(suggestionTypes, suggType)
 	}
 	errorMsg := ""
@@ -101,6 +104,12 @@ func (bc *BotController) suggestionsHandleAdd(m *tb.Message, params ...string) {
 		return
 	}
 	for _, value := range singleValues {
+		if h.TypeCacheKey(suggestionType) == h.FIELD_CURRENCY {
+			if err := h.IsValidCommodity(value); err != nil {
+				bc.suggestionsHelp(m, err)
+				return
+			}
+		}
 		err := bc.Repo.PutCacheHints(m, map[string]string{suggestionType: value})
 		if err != nil {
 			bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Error encountered while adding suggestion (%s): %s", value, err.Error()))
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// TemplateArg is a value given for a template field by its name when using the template, e.g. 'amount=3.50' or 'account:from=Assets:Cash'
+type TemplateArg struct {
+	Key   string
+	Value string
+}
+
+var namedTemplateArgPattern = regexp.MustCompile(`^([a-z]+(?::[^=\s]+)?)=(.*)$`)
+
+// Dates given with digits only (e.g. '11' or '-2') can't be told apart from amounts. These need to be given as 'date=-2'.
+var numericDatePattern = regexp.MustCompile(`^\d+-\d+(-\d+)?$`)
+
+func isUnambiguousDate(s string) bool {
+	if _, err := ParseDate(s, 0); err != nil {
+		return false
+	}
+	return numericDatePattern.MatchString(s) || strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// splitTemplateArgs separates the arguments given when using a template into named values, positional values and the date.
+// The date is either given as 'date=<date>' or as the last argument(s), e.g. '2026-10-16', 'yesterday' or 'last friday'.
+// Quoted arguments (isQuoted) are always taken as values, e.g. '"Friday"' as description.
+func splitTemplateArgs(args []string, isQuoted []bool) (named []*TemplateArg, positional []string, date string) {
+	positionalQuoted := []bool{}
+	for i, arg := range args {
+		match := namedTemplateArgPattern.FindStringSubmatch(arg)
+		if match == nil {
+			positional = append(positional, arg)
+			positionalQuoted = append(positionalQuoted, i < len(isQuoted) && isQuoted[i])
+			continue
+		}
+		if match[1] == c.FIELD_DATE {
+			date = match[2]
+			continue
+		}
+		named = append(named, &TemplateArg{Key: match[1], Value: match[2]})
+	}
+	if date != "" {
+		return
+	}
+	count := len(positional)
+	if count >= 2 && !positionalQuoted[count-2] && !positionalQuoted[count-1] && isUnambiguousDate(strings.Join(positional[count-2:], " ")) {
+		return named, positional[:count-2], strings.Join(positional[count-2:], " ")
+	}
+	if count >= 1 && !positionalQuoted[count-1] && isUnambiguousDate(positional[count-1]) {
+		return named, positional[:count-1], positional[count-1]
+	}
+	return
+}
+
+// templateArgKey is the name a field is referred to by in named arguments, e.g. 'amount' or 'account:from'
+func templateArgKey(f *TemplateField) string {
+	return strings.TrimSuffix(f.FieldIdentifierForValue(), ":")
+}
+
+// Prefill answers fields with the values given when using the template. Named values are applied first,
+// positional values fill the remaining fields in the order they would be asked for. Fields left are asked for as usual.
+// Accounts need to be open in the account registry, unless it is empty.
+func (tx *SimpleTx) Prefill(named []*TemplateArg, positional []string, registry []string) error {
+	for _, arg := range named {
+		idx := -1
+		for i, f := range tx.nextFields {
+			_, isFilled := tx.data[f.FieldIdentifierForValue()]
+			_, isAsked := TEMPLATE_TYPE_HINTS[Type(f.FieldName)]
+			if isAsked && !isFilled && templateArgKey(f) == arg.Key {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("the template has no variable '%s' to fill with '%s'", arg.Key, arg.Value)
+		}
+		nextFields := []*TemplateField{tx.nextFields[idx]}
+		nextFields = append(nextFields, tx.nextFields[:idx]...)
+		tx.nextFields = append(nextFields, tx.nextFields[idx+1:]...)
+		if err := tx.prefillNextField(arg.Value, registry); err != nil {
+			return err
+		}
+	}
+	for _, value := range positional {
+		if tx.IsDone() {
+			return fmt.Errorf("the template has no variable left to fill with '%s'", value)
+		}
+		if err := tx.prefillNextField(value, registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *SimpleTx) prefillNextField(value string, registry []string) error {
+	f := tx.NextField()
+	if f.FieldName == c.FIELD_ACCOUNT && len(registry) > 0 && !c.ArrayContains(registry, value) && !(f.IsOptional && value == SKIP_OPTIONAL) {
+		return fmt.Errorf("the account '%s' is not open in your registry of accounts (/%s)", value, CMD_ACCOUNTS)
+	}
+	if _, err := tx.Input(&tb.Message{Text: value}); err != nil {
+		return fmt.Errorf("invalid value '%s' for '%s': %s", value, templateArgKey(f), err.Error())
+	}
+	return nil
+}
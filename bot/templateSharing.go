@@ -0,0 +1,245 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// Shared templates can be imported through the link 't.me/<bot>?start=tpl_<code>'
+const (
+	TEMPLATE_SHARE_PREFIX     = "tpl_"
+	TEMPLATE_SHARE_CODE_BYTES = 8
+
+	TEMPLATE_EXPORT_FILENAME = "templates.json"
+	TEMPLATE_IMPORT_MAX_SIZE = 1 << 20
+)
+
+// TemplateExport is a template as written to and read from template files
+type TemplateExport struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+	Template string `json:"template"`
+}
+
+func newShareCode() (string, error) {
+	code := make([]byte, TEMPLATE_SHARE_CODE_BYTES)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(code), nil
+}
+
+func (bc *BotController) templatesHandleShare(m *tb.Message, params ...string) {
+	if len(params) != 1 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	code, err := newShareCode()
+	if err == nil {
+		err = bc.Repo.ShareTemplate(m.Chat.ID, code, tpl.Name, tpl.Template)
+	}
+	if err != nil {
+		bc.Logf(ERROR, m, "Sharing template failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while sharing your template.")
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template '%s' can now be imported by other chats by opening this link:\nhttps://t.me/%s?start=%s%s\n\nAlternatively, type '/%s import %s' in the other chat. "+
+		"The imported template is a copy: Later changes to your template are not shared. To share them, please share the template again.",
+		tpl.Name, bc.Bot.Me().Username, TEMPLATE_SHARE_PREFIX, code, CMD_TEMPLATE[1], code))
+}
+
+func (bc *BotController) templatesHandleImport(m *tb.Message, params ...string) {
+	if len(params) < 1 || len(params) > 2 {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("To import a shared template, type '/%s import <code> [name]'. "+
+			"To import templates exported with '/%s export', please send the file to this chat (in group chats with the caption '/%s import').", CMD_TEMPLATE[1], CMD_TEMPLATE[1], CMD_TEMPLATE[1]))
+		return
+	}
+	name := ""
+	if len(params) == 2 {
+		name = params[1]
+	}
+	bc.importSharedTemplate(m, strings.TrimPrefix(params[0], TEMPLATE_SHARE_PREFIX), name)
+}
+
+// importSharedTemplate adds a copy of the shared template to the chat, under its original name if none is given
+func (bc *BotController) importSharedTemplate(m *tb.Message, code, name string) {
+	shared, err := bc.Repo.GetSharedTemplate(m, code)
+	if err != nil {
+		bc.Logf(ERROR, m, "Getting shared template failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while importing the shared template.")
+		return
+	}
+	if shared == nil {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There is no template shared under the code '%s'. Please check the code or ask for the template to be shared again.", code))
+		return
+	}
+	if name == "" {
+		name = shared.Name
+	}
+	if err := validateTemplate(shared.Template); err != nil {
+		// Shares created before templates have been validated might not be usable
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("The shared template '%s' can't be imported: %s", shared.Name, err.Error()))
+		return
+	}
+	if bc.templateExists(m, name) {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There already is a template called '%s'. To import the shared template under another name, type '/%s import %s <name>'.", name, CMD_TEMPLATE[1], code))
+		return
+	}
+	err = bc.Repo.AddTemplate(m.Chat.ID, name, shared.Template)
+	if err != nil {
+		bc.Logf(ERROR, m, "Importing shared template failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while importing the shared template.")
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully imported the template '%s':\n%s\n\nYou can use it from now on by typing '/%s %s'.", name, shared.Template, CMD_TEMPLATE[1], name))
+}
+
+func (bc *BotController) templatesHandleExport(m *tb.Message, params ...string) {
+	templates, err := bc.Repo.GetTemplates(m, "")
+	if err != nil {
+		bc.Logf(ERROR, m, "Error loading templates: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "There has been an error loading your templates.")
+		return
+	}
+	if len(templates) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), "You have not created any template yet. Please see /template")
+		return
+	}
+	usages, err := bc.Repo.GetTemplateUsage(m)
+	if err != nil {
+		bc.Logf(ERROR, m, "Error loading template categories: %s", err.Error())
+		// Don't return, templates are exported without categories
+	}
+	categories := map[string]string{}
+	for _, u := range usages {
+		categories[u.Name] = u.Category
+	}
+	exports := []*TemplateExport{}
+	for _, t := range templates {
+		exports = append(exports, &TemplateExport{Name: t.Name, Category: categories[t.Name], Template: t.Template})
+	}
+	data, err := json.MarshalIndent(exports, "", "  ")
+	if err != nil {
+		bc.Logf(ERROR, m, "Error exporting templates: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while exporting your templates.")
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), &tb.Document{
+		File:     tb.FromReader(bytes.NewReader(data)),
+		FileName: TEMPLATE_EXPORT_FILENAME,
+		MIME:     "application/json",
+		Caption:  fmt.Sprintf("Your %d templates. To import them into another chat, send this file there.", len(exports)),
+	})
+}
+
+// isTemplateImport tells whether a document is sent to be imported as template file. In group chats, this requires
+// a reply to the bot or the caption '/t import', so that other files shared by the members are ignored.
+func (bc *BotController) isTemplateImport(m *tb.Message) bool {
+	if m.Sender == nil || !crud.IsGroupChat(m) {
+		return true
+	}
+	if m.ReplyTo != nil && m.ReplyTo.Sender != nil && m.ReplyTo.Sender.ID == bc.Bot.Me().ID {
+		return true
+	}
+	caption := strings.Fields(m.Caption)
+	if len(caption) < 2 || !strings.HasPrefix(caption[0], "/") || caption[1] != "import" {
+		return false
+	}
+	command := strings.SplitN(strings.TrimPrefix(caption[0], "/"), "@", 2)[0]
+	return h.ArrayContains(CMD_TEMPLATE, command)
+}
+
+// handleDocument imports template files sent to the bot
+func (bc *BotController) handleDocument(c tb.Context) error {
+	m := c.Message()
+	if m.Document == nil {
+		return nil
+	}
+	if !bc.isTemplateImport(m) {
+		bc.Logf(DEBUG, m, "Ignoring document in group chat")
+		return nil
+	}
+	if !strings.HasSuffix(strings.ToLower(m.Document.FileName), ".json") {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Only template files exported with '/%s export' can be imported.", CMD_TEMPLATE[1]))
+		return nil
+	}
+	if m.Document.FileSize > TEMPLATE_IMPORT_MAX_SIZE {
+		bc.Bot.SendSilent(bc, Recipient(m), "The template file is too large to be imported.")
+		return nil
+	}
+	reader, err := bc.Bot.File(&m.Document.File)
+	if err != nil {
+		bc.Logf(ERROR, m, "Downloading template file failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while reading your template file.")
+		return nil
+	}
+	defer reader.Close()
+	exports := []*TemplateExport{}
+	err = json.NewDecoder(io.LimitReader(reader, TEMPLATE_IMPORT_MAX_SIZE)).Decode(&exports)
+	if err != nil {
+		bc.Logf(INFO, m, "Could not parse template file: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("The file could not be read as template file: %s\n\nPlease send a file exported with '/%s export'.", err.Error(), CMD_TEMPLATE[1]))
+		return nil
+	}
+	bc.importTemplates(m, exports)
+	return nil
+}
+
+// importTemplates adds the templates of a template file. Templates with names already taken or invalid ones are skipped.
+func (bc *BotController) importTemplates(m *tb.Message, exports []*TemplateExport) {
+	imported := []string{}
+	skipped := []string{}
+	for _, e := range exports {
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			skipped = append(skipped, "- template without name")
+			continue
+		}
+		if err := validateTemplateName(name); err != nil {
+			skipped = append(skipped, fmt.Sprintf("- %s: %s", name, err.Error()))
+			continue
+		}
+		if err := validateTemplate(e.Template); err != nil {
+			skipped = append(skipped, fmt.Sprintf("- %s: %s", name, err.Error()))
+			continue
+		}
+		if bc.templateExists(m, name) {
+			skipped = append(skipped, fmt.Sprintf("- %s: there already is a template with this name", name))
+			continue
+		}
+		if err := bc.Repo.AddTemplate(m.Chat.ID, name, e.Template); err != nil {
+			bc.Logf(ERROR, m, "Importing template '%s' failed: %s", name, err.Error())
+			skipped = append(skipped, fmt.Sprintf("- %s: the template could not be saved", name))
+			continue
+		}
+		if e.Category != "" {
+			if err := bc.Repo.SetTemplateCategory(m.Chat.ID, name, e.Category); err != nil {
+				bc.Logf(ERROR, m, "Setting category of imported template '%s' failed: %s", name, err.Error())
+			}
+		}
+		imported = append(imported, name)
+	}
+	message := fmt.Sprintf("Imported %d templates", len(imported))
+	if len(imported) > 0 {
+		message += ": " + strings.Join(imported, ", ")
+	}
+	message += "."
+	if len(skipped) > 0 {
+		message += "\n\nThese templates have been skipped:\n" + strings.Join(skipped, "\n")
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), message)
+}
@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestTemplateShareAndImport(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	otherChat := &tb.Chat{ID: 67890}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	template := "${date} * \"Coffee\"\n  Assets:Cash ${-amount}\n  Expenses:Coffee"
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "cof%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("coffee", template))
+	mock.
+		ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::templateShare" ("code", "tgChatId", "name", "template")`)).
+		WithArgs(sqlmock.AnyArg(), 12345, "coffee", template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t share cof"}})
+	link := regexp.MustCompile(`https://t\.me/Test bot\?start=tpl_([0-9a-f]{16})`).FindStringSubmatch(fmt.Sprintf("%v", bot.LastSentWhat))
+	if link == nil {
+		t.Fatalf("share message should contain link: %v", bot.LastSentWhat)
+	}
+	code := link[1]
+
+	sharedTemplate := func(code string, rows *sqlmock.Rows) {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::templateShare"`)).
+			WithArgs(code).
+			WillReturnRows(rows)
+	}
+	existingTemplates := func(name string, rows *sqlmock.Rows) {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+			WithArgs(67890, name+"%").
+			WillReturnRows(rows)
+	}
+	sharedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"name", "template"}).AddRow("coffee", template)
+	}
+
+	// Import through link
+	sharedTemplate(code, sharedRows())
+	existingTemplates("coffee", sqlmock.NewRows([]string{"name", "template"}))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::template" ("tgChatId", "name", "template") VALUES ($1, $2, $3)`)).
+		WithArgs(67890, "coffee", template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandStart(&MockContext{M: &tb.Message{Chat: otherChat, Text: "/start tpl_" + code}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully imported the template 'coffee'", "import through link")
+
+	// Name already taken
+	sharedTemplate(code, sharedRows())
+	existingTemplates("coffee", sqlmock.NewRows([]string{"name", "template"}).AddRow("coffee", template))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: otherChat, Text: "/t import " + code}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), fmt.Sprintf("type '/t import %s <name>'", code), "import under another name")
+
+	sharedTemplate(code, sharedRows())
+	existingTemplates("cafe", sqlmock.NewRows([]string{"name", "template"}))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::template" ("tgChatId", "name", "template") VALUES ($1, $2, $3)`)).
+		WithArgs(67890, "cafe", template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: otherChat, Text: "/t import tpl_" + code + " cafe"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully imported the template 'cafe'", "import under another name")
+
+	sharedTemplate("1111", sqlmock.NewRows([]string{"name", "template"}).AddRow("broken", "${date} * \"Broken\"\n  Assets:Cash ${-amount"))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: otherChat, Text: "/t import 1111"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "The shared template 'broken' can't be imported: the variable '${-amount' is not closed with '}'", "invalid shared template")
+
+	sharedTemplate("0000", sqlmock.NewRows([]string{"name", "template"}))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: otherChat, Text: "/t import 0000"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "There is no template shared under the code '0000'", "unknown code")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplateExportAndImportFile(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	otherChat := &tb.Chat{ID: 67890}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = ?`).
+		WithArgs(12345).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).
+			AddRow("coffee", "${date} * \"Coffee\"\n  Assets:Cash ${-amount}\n  Expenses:Coffee").
+			AddRow("rent", "${date} * \"Rent\"\n  Assets:Checking -800.00 EUR\n  Expenses:Rent"))
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "category", "usageCount" FROM "bot::template"`)).
+		WithArgs(12345).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category", "usageCount"}).
+			AddRow("coffee", "Food", 3).
+			AddRow("rent", "", 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t export"}})
+	document, isDocument := bot.LastSentWhat.(*tb.Document)
+	if !isDocument {
+		t.Fatalf("export should send a document: %v", bot.LastSentWhat)
+	}
+	helpers.TestExpect(t, document.FileName, "templates.json", "file name")
+	data, err := io.ReadAll(document.FileReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := []*TemplateExport{}
+	if err := json.Unmarshal(data, &exports); err != nil {
+		t.Fatal(err)
+	}
+	helpers.TestExpect(t, len(exports), 2, "exported templates")
+	helpers.TestExpect(t, exports[0].Category, "Food", "exported category")
+
+	// Import into other chat, with an invalid template and an unusable name added
+	exports = append(exports, &TemplateExport{Name: "broken", Template: "${date} * \"Broken\"\n  Assets:Cash ${-amount"})
+	exports = append(exports, &TemplateExport{Name: "night bus", Template: "${date} * \"Bus\"\n  Assets:Cash ${-amount}\n  Expenses:Transport"})
+	data, _ = json.Marshal(exports)
+	bot.Files = map[string]string{"file1": string(data)}
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(67890, "coffee%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::template" ("tgChatId", "name", "template") VALUES ($1, $2, $3)`)).
+		WithArgs(67890, "coffee", exports[0].Template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "bot::template" SET "category" = $3`)).
+		WithArgs(67890, "coffee", "Food").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(67890, "rent%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("rent", "other rent template"))
+	bc.handleDocument(&MockContext{M: &tb.Message{Chat: otherChat, Document: &tb.Document{File: tb.File{FileID: "file1"}, FileName: "templates.json"}}})
+	helpers.TestExpect(t, fmt.Sprintf("%v", bot.LastSentWhat), `Imported 1 templates: coffee.
+
+These templates have been skipped:
+- rent: there already is a template with this name
+- broken: the variable '${-amount' is not closed with '}'
+- night bus: template names can't contain spaces`, "import result")
+
+	bc.handleDocument(&MockContext{M: &tb.Message{Chat: otherChat, Sender: &tb.User{ID: otherChat.ID}, Document: &tb.Document{File: tb.File{FileID: "file2"}, FileName: "receipt.pdf"}}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Only template files exported with '/t export' can be imported", "other documents")
+
+	// Group chats
+	groupChat := &tb.Chat{ID: -100}
+	member := &tb.User{ID: 42}
+	bot.Files["file3"] = "not a template file"
+	bot.LastSentWhat = nil
+	bc.handleDocument(&MockContext{M: &tb.Message{Chat: groupChat, Sender: member, Document: &tb.Document{File: tb.File{FileID: "file3"}, FileName: "data.json"}}})
+	helpers.TestExpect(t, bot.LastSentWhat, nil, "documents not addressed to the bot should be ignored in group chats")
+
+	bc.handleDocument(&MockContext{M: &tb.Message{Chat: groupChat, Sender: member, Caption: "/t import", Document: &tb.Document{File: tb.File{FileID: "file3"}, FileName: "data.json"}}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "The file could not be read as template file", "import with caption")
+
+	bot.LastSentWhat = nil
+	bc.handleDocument(&MockContext{M: &tb.Message{Chat: groupChat, Sender: member, ReplyTo: &tb.Message{Sender: bot.Me()}, Document: &tb.Document{File: tb.File{FileID: "file3"}, FileName: "data.json"}}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "The file could not be read as template file", "import in reply to the bot")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -2,8 +2,12 @@ package bot
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
 	h "github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
 )
@@ -15,12 +19,24 @@ func (bc *BotController) templatesHandler(m *tb.Message) {
 	}
 	sc := h.MakeSubcommandHandler("/"+base, true)
 	sc.
+		Add("help", func(m *tb.Message, params ...string) { bc.templatesHelp(m, nil) }).
 		Add("list", bc.templatesHandleList).
 		Add("add", bc.templatesHandleAdd).
+		Add("show", bc.templatesHandleShow).
+		Add("edit", bc.templatesHandleEdit).
+		Add("rename", bc.templatesHandleRename).
+		Add("category", bc.templatesHandleCategory).
+		Add("history", bc.templatesHandleHistory).
+		Add("restore", bc.templatesHandleRestore).
+		Add("preview", bc.templatesHandlePreview).
+		Add("share", bc.templatesHandleShare).
+		Add("import", bc.templatesHandleImport).
+		Add("export", bc.templatesHandleExport).
 		Add("rm", bc.templatesHandleRemove)
 	parameters, err := sc.Handle(m)
 	if err != nil {
-		useErr := bc.templatesUse(m, parameters...)
+		args, isQuoted := h.SplitQuotedCommandTokens(strings.Join(parameters, " "))
+		useErr := bc.templatesUse(m, args, isQuoted)
 		if useErr != nil {
 			bc.Logf(ERROR, m, "could not handle templates command: %s - previous error for regular handle: %s", useErr.Error(), err.Error())
 			bc.templatesHelp(m, useErr)
@@ -36,14 +52,25 @@ func (bc *BotController) templatesHelp(m *tb.Message, err error) {
 	bc.Bot.SendSilent(bc, Recipient(m), errorMsg+`Usage help for /template:
 	/template list [name]
 	/template add <name>
+	/template show <name>
+	/template edit <name>
+	/template rename <name> <newName>
+	/template category <name> [category]
+	/template history <name>
+	/template restore <name> <version>
+	/template preview <name>
+	/template share <name>
+	/template import <code> [name]
+	/template export
 	/template rm <name>
 	
-	To use an existing template, type:
-	/template <name> [date]
+	To use an existing template, select it after typing /t or type:
+	/template <name> [values...] [date]
 	or use the short form:
-	/t <name> [date]
+	/t <name> [values...] [date]
 	
-	If omitted, date defaults to today.`)
+	Values fill the variables of the template in the order they would be asked for, e.g. '/t coffee 3.50 "Bakery"'. Variables can also be filled by name, e.g. 'amount=3.50' or 'account:from=Assets:Cash'. Variables left are asked for as usual.
+	If omitted, date defaults to today. Relative dates like 'yesterday' or 'last friday' can be used as well. Dates given with digits only (e.g. '11' or '-2') need to be given as 'date=-2'.`)
 }
 
 func (bc *BotController) templatesHandleList(m *tb.Message, params ...string) {
@@ -85,18 +112,29 @@ func (bc *BotController) templatesHandleAdd(m *tb.Message, params ...string) {
 		return
 	}
 	name := params[0]
-	if strings.TrimSpace(name) == "" {
-		bc.templatesHelp(m, fmt.Errorf("please name your template"))
+	if err := validateTemplateName(name); err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	if bc.templateExists(m, name) {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There already is a template called '%s'. Please choose another name or change the existing template using '/t edit %s'.", name, name))
 		return
 	}
 	bc.State.StartTpl(m, name)
 	bc.Bot.SendSilent(bc, Recipient(m), `Please provide a full transaction template. Variables are to be inserted as '${<variable>}'. The following variables can be used:
 - ${amount}, ${-amount}, ${amount/i} (e.g. ${amount/2})
+- Expressions over the amount using + - * / % and parentheses, e.g. ${amount*19%}, ${amount/1.19} or ${-amount-1.50}
+- ${amount:<yourName>:<yourHint>:<currency>} (multiple amounts, each with its own hint and optionally its own currency, e.g. ${amount:received:the money *received*:USD})
+- ${-amount:sent} @@ ${amount:received} (annotates the amount sent with the total received, so that transfers between currencies balance. Left out if both amounts are in the same currency)
 - ${date}
 - ${description}
+- ${payee} (e.g. '"${payee?}" "${description}"'. Descriptions used with the chosen payee before are suggested first)
+- ${meta:<key>:<yourHint>} (adds the metadata line '<key>: "<value>"' below the transaction header or posting the variable is placed in)
+- ${flag} (asks whether the transaction is completed '*' or pending '!')
 - ${account:from}
 - ${account:to}
 - ${account:<yourName>:<yourHint>}
+Appending '?' to a variable name makes it optional, so it can be skipped when using the template (e.g. ${description?}).
 
 Example:
 
@@ -129,31 +167,430 @@ func (bc *BotController) templatesHandleRemove(m *tb.Message, params ...string)
 
 func (bc *BotController) processNewTemplateResponse(m *tb.Message, name TemplateName) (clearState bool) {
 	template := m.Text
-	err := bc.Repo.AddTemplate(m.Chat.ID, string(name), template)
-	if err != nil {
-		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while saving your template. Please check whether the name already exists.")
+	isEdit := bc.State.IsTplEdit(m)
+	operation := "creation"
+	if isEdit {
+		operation = "edit"
+	}
+	if err := validateTemplate(template); err != nil {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template could not be saved: %s\n\nPlease send the corrected template or /%s the %s.", err.Error(), CMD_CANCEL, operation))
 		return false
 	}
-	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully created your template. You can use it from now on by typing '/t %s' (/t is short for /template).", name))
+	message := fmt.Sprintf("Successfully created your template. You can use it from now on by typing '/t %s' (/t is short for /template).", name)
+	if isEdit {
+		err := bc.Repo.UpdateTemplate(m.Chat.ID, string(name), template)
+		if err != nil {
+			bc.Logf(ERROR, m, "Updating template failed: %s", err.Error())
+			bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while saving your template: "+err.Error())
+			return false
+		}
+		message = fmt.Sprintf("Successfully updated your template '%s'. The previous version can be restored using '/t history %s'.", name, name)
+	} else {
+		err := bc.Repo.AddTemplate(m.Chat.ID, string(name), template)
+		if err != nil {
+			bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while saving your template. Please check whether the name already exists.")
+			return false
+		}
+	}
+	if prompts := templatePrompts(template); len(prompts) > 0 {
+		message += "\n\nUsing it, you will be asked for:\n" + strings.Join(prompts, "\n")
+	}
+	message += fmt.Sprintf("\n\nTo see what the resulting transactions look like, type '/t preview %s'.", name)
+	bc.Bot.SendSilent(bc, Recipient(m), message)
 	return true
 }
 
-type TemplateTx struct {
+// Fields filled automatically instead of being asked for
+var templateAutoFilledFields = []string{h.FIELD_DATE, h.FIELD_TAG}
+
+// Metadata keys as allowed by the beancount grammar, e.g. 'receipt' or 'invoice-id'
+var metaKeyPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9_-]*$`)
+
+// validateTemplateName rejects names that can't be used with '/t <name>', as only the first word is taken as the name there
+func validateTemplateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("please name your template")
+	}
+	if strings.ContainsAny(name, " \t\n\r") {
+		return fmt.Errorf("template names can't contain spaces")
+	}
+	return nil
+}
+
+// validateTemplate rejects templates that would fail when being used, e.g. because of unclosed variables or unknown variable types
+func validateTemplate(template string) error {
+	for rest := template; strings.Contains(rest, "${"); {
+		rest = rest[strings.Index(rest, "${")+2:]
+		end := strings.Index(rest, "}")
+		if end < 0 || strings.Contains(rest[:end], "${") {
+			return fmt.Errorf("the variable '${%s' is not closed with '}'", strings.SplitN(strings.SplitN(rest, "\n", 2)[0], "${", 2)[0])
+		}
+		if strings.TrimSpace(rest[:end]) == "" {
+			return fmt.Errorf("the template contains an empty variable '${}'")
+		}
+		rest = rest[end+1:]
+	}
+	if !strings.Contains(template, "${") {
+		// Templates without variables are recorded as they are
+		return nil
+	}
+	for _, f := range ParseTemplateFields(template, "") {
+		if _, isAsked := TEMPLATE_TYPE_HINTS[Type(f.FieldName)]; !isAsked && !h.ArrayContains(templateAutoFilledFields, f.FieldName) {
+			return fmt.Errorf("the variable '${%s}' has the unknown type '%s'. Please see '/t add' for the variables available", f.Raw, f.FieldName)
+		}
+		if f.FieldName == h.FIELD_META && f.FieldSpecifier == "" {
+			return fmt.Errorf("the metadata variable '${%s}' needs a key, e.g. '${%s:receipt}'", f.Raw, h.FIELD_META)
+		}
+		if f.FieldName == h.FIELD_META && !metaKeyPattern.MatchString(f.FieldSpecifier) {
+			return fmt.Errorf("the metadata key '%s' of the variable '${%s}' is invalid: keys need to start with a lowercase letter, followed by letters, digits, '-' or '_'", f.FieldSpecifier, f.Raw)
+		}
+		if f.Expression == "" {
+			continue
+		}
+		if err := ValidateAmountExpression(f.Expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templatePrompts lists the fields asked for when using the template, in the order they are asked for
+func templatePrompts(template string) []string {
+	prompts := []string{}
+	asked := []string{}
+	for _, f := range ParseTemplateFields(template, "") {
+		if _, isAsked := TEMPLATE_TYPE_HINTS[Type(f.FieldName)]; !isAsked || h.ArrayContains(asked, f.FieldIdentifierForValue()) {
+			continue
+		}
+		asked = append(asked, f.FieldIdentifierForValue())
+		prompt := fmt.Sprintf("%d. %s", len(asked), f.FieldName)
+		if f.FieldHint != "" {
+			prompt += " " + strings.ReplaceAll(f.FieldHint, "*", "")
+		}
+		if f.Currency != "" {
+			prompt += fmt.Sprintf(" (in %s)", f.Currency)
+		}
+		if f.IsOptional {
+			prompt += " (optional)"
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts
+}
+
+// templateSampleValue is the value filled in for the field when previewing a template
+func templateSampleValue(f *TemplateField) string {
+	switch f.FieldName {
+	case h.FIELD_AMOUNT:
+		return withCurrency(FORMATTER_PLACEHOLDER+"12.34", f.Currency)
+	case h.FIELD_ACCOUNT:
+		if f.FieldSpecifier == "" {
+			return "Assets:Sample"
+		}
+		return "Assets:Sample:" + strings.ToUpper(f.FieldSpecifier[:1]) + f.FieldSpecifier[1:]
+	case h.FIELD_PAYEE:
+		return "Sample payee"
+	case h.FIELD_DESCRIPTION:
+		return "Sample description"
+	case h.FIELD_META:
+		return "sample"
+	case h.FIELD_FLAG:
+		return FLAG_COMPLETED
+	}
+	// e.g. posting: No further postings
+	return ""
+}
+
+// previewTemplate fills the template with sample values for all fields asked for
+func previewTemplate(template, currency string, tzOffset int) (string, error) {
+	tx := &SimpleTx{
+		data:                   make(map[string]string),
+		template:               template,
+		userCurrencySuggestion: currency,
+	}
+	tx.Prepare()
+	for f := tx.NextField(); f != nil; f = tx.NextField() {
+		tx.data[f.FieldIdentifierForValue()] = templateSampleValue(f)
+	}
+	return tx.FillTemplate(currency, "", tzOffset)
+}
+
+func (bc *BotController) templatesHandlePreview(m *tb.Message, params ...string) {
+	if len(params) != 1 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	preview, err := previewTemplate(tpl.Template, bc.Repo.UserGetCurrency(m), bc.Repo.UserGetTzOffset(m))
+	if err != nil {
+		bc.Logf(ERROR, m, "Previewing template failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template '%s' could not be filled: %s", tpl.Name, err.Error()))
+		return
+	}
+	message := fmt.Sprintf("This is what transactions from your template '%s' look like (using sample values):\n\n%s", tpl.Name, preview)
+	if prompts := templatePrompts(tpl.Template); len(prompts) > 0 {
+		message += "\nUsing it, you will be asked for:\n" + strings.Join(prompts, "\n")
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), message)
 }
 
-func (bc *BotController) templatesUse(m *tb.Message, params ...string) error {
+func (bc *BotController) templatesHandleCategory(m *tb.Message, params ...string) {
 	if len(params) < 1 || len(params) > 2 {
-		return fmt.Errorf("parameter count mismatch")
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
 	}
-	name := params[0]
-	var date string
-	if len(params) >= 2 {
-		date = params[1]
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
 	}
-	if name == "" {
+	category := ""
+	if len(params) == 2 {
+		category = strings.TrimSpace(params[1])
+	}
+	err = bc.Repo.SetTemplateCategory(m.Chat.ID, tpl.Name, category)
+	if err != nil {
+		bc.Logf(ERROR, m, "Setting template category failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while setting the category of your template.")
+		return
+	}
+	if category == "" {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template '%s' has been removed from its category.", tpl.Name))
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template '%s' is now listed in the category '%s'.", tpl.Name, category))
+}
+
+const (
+	TEMPLATE_PICKER_ROW_SIZE = 3
+	TEMPLATE_CATEGORY_NONE   = "Other"
+)
+
+// groupTemplatesByCategory groups the template names by category. Categories and templates are ordered by usage,
+// templates without category come last.
+func groupTemplatesByCategory(usages []*crud.TemplateUsage) (categories []string, templates map[string][]string) {
+	templates = map[string][]string{}
+	categoryUsage := map[string]int{}
+	for _, u := range usages {
+		category := u.Category
+		if category == "" {
+			category = TEMPLATE_CATEGORY_NONE
+		}
+		if _, exists := templates[category]; !exists {
+			categories = append(categories, category)
+		}
+		templates[category] = append(templates[category], u.Name)
+		categoryUsage[category] += u.UsageCount
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if (categories[i] == TEMPLATE_CATEGORY_NONE) != (categories[j] == TEMPLATE_CATEGORY_NONE) {
+			return categories[j] == TEMPLATE_CATEGORY_NONE
+		}
+		return categoryUsage[categories[i]] > categoryUsage[categories[j]]
+	})
+	return categories, templates
+}
+
+// templatePickerButton starts the template when being selected
+func templatePickerButton(name string) string {
+	if strings.Contains(name, " ") {
+		name = `"` + name + `"`
+	}
+	return fmt.Sprintf("/%s %s", CMD_TEMPLATE[1], name)
+}
+
+// templatesPicker offers the templates as keyboard buttons, one row per category
+func (bc *BotController) templatesPicker(m *tb.Message) {
+	usages, err := bc.Repo.GetTemplateUsage(m)
+	if err != nil {
+		bc.Logf(ERROR, m, "Error loading templates: %s", err.Error())
+	}
+	if len(usages) == 0 {
 		bc.templatesHelp(m, nil)
+		return
+	}
+	categories, templates := groupTemplatesByCategory(usages)
+	categoryList := []string{}
+	rows := [][]string{}
+	for _, category := range categories {
+		categoryList = append(categoryList, fmt.Sprintf("%s: %s", category, strings.Join(templates[category], ", ")))
+		row := []string{}
+		for _, name := range templates[category] {
+			if len(row) == TEMPLATE_PICKER_ROW_SIZE {
+				rows = append(rows, row)
+				row = []string{}
+			}
+			row = append(row, templatePickerButton(name))
+		}
+		rows = append(rows, row)
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Please select the template to use. Your templates by category:\n%s\n\nFor further template commands, please see '/%s help'.",
+		strings.Join(categoryList, "\n"), CMD_TEMPLATE[1]), ReplyKeyboardRows(rows))
+}
+
+// getTemplate returns the template identified uniquely by (a prefix of) its name
+func (bc *BotController) getTemplate(m *tb.Message, name string) (*crud.TemplateResult, error) {
+	res, err := bc.Repo.GetTemplates(m, name)
+	if err != nil {
+		bc.Logf(ERROR, m, "Getting template failed: %s", err.Error())
+		return nil, fmt.Errorf("unable to get the template you specified from the database at the moment")
+	}
+	if len(res) != 1 {
+		return nil, fmt.Errorf("could not find the template you specified. Please check '/t list'")
+	}
+	return res[0], nil
+}
+
+// templateExists checks whether there is a template with exactly this name
+func (bc *BotController) templateExists(m *tb.Message, name string) bool {
+	res, err := bc.Repo.GetTemplates(m, name)
+	if err != nil {
+		bc.Logf(ERROR, m, "Checking for existing templates failed: %s", err.Error())
+		return false
+	}
+	for _, t := range res {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (bc *BotController) templatesHandleShow(m *tb.Message, params ...string) {
+	if len(params) != 1 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("%s:\n%s", tpl.Name, tpl.Template), clearKeyboard())
+}
+
+func (bc *BotController) templatesHandleEdit(m *tb.Message, params ...string) {
+	if bc.State.GetType(m) != ST_NONE {
+		bc.Bot.SendSilent(bc, Recipient(m), "There is another operation currently running for you. Please complete it or /cancel it before proceeding.")
+		return
+	}
+	if len(params) != 1 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	bc.State.StartTplEdit(m, tpl.Name)
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Please provide the new version of your template '%s'. Its current version is:\n\n%s\n\nFor the variables available, please see '/t add'. To keep the template unchanged, /%s the edit.", tpl.Name, tpl.Template, CMD_CANCEL))
+}
+
+func (bc *BotController) templatesHandleRename(m *tb.Message, params ...string) {
+	if len(params) != 2 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	newName := params[1]
+	if bc.templateExists(m, newName) {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There already is a template called '%s'. Please choose another name.", newName))
+		return
+	}
+	err = bc.Repo.RenameTemplate(m.Chat.ID, tpl.Name, newName)
+	if err != nil {
+		bc.Logf(ERROR, m, "Renaming template failed: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while renaming your template.")
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully renamed your template '%s' to '%s'.", tpl.Name, newName))
+}
+
+func (bc *BotController) templatesHandleHistory(m *tb.Message, params ...string) {
+	if len(params) != 1 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	versions, err := bc.Repo.GetTemplateHistory(m, tpl.Name)
+	if err != nil {
+		bc.Logf(ERROR, m, "Error loading template history: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "There has been an error loading the previous versions of your template.")
+		return
+	}
+	if len(versions) == 0 {
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Your template '%s' has not been edited yet.", tpl.Name))
+		return
+	}
+	versionList := []string{fmt.Sprintf("These are the previous versions of your template '%s', most recent first. To restore one, type '/t restore %s <version>'.", tpl.Name, tpl.Name)}
+	for _, v := range versions {
+		versionList = append(versionList, fmt.Sprintf("Version %d (replaced %s):\n%s", v.Id, v.Replaced.Format("2006-01-02 15:04"), v.Template))
+	}
+	for _, message := range bc.MergeMessagesHonorSendLimit(versionList, "\n\n") {
+		bc.Bot.SendSilent(bc, Recipient(m), message, clearKeyboard())
+	}
+}
+
+func (bc *BotController) templatesHandleRestore(m *tb.Message, params ...string) {
+	if len(params) != 2 {
+		bc.templatesHelp(m, fmt.Errorf("parameter count mismatch"))
+		return
+	}
+	tpl, err := bc.getTemplate(m, params[0])
+	if err != nil {
+		bc.templatesHelp(m, err)
+		return
+	}
+	versionId, err := strconv.Atoi(params[1])
+	if err != nil {
+		bc.templatesHelp(m, fmt.Errorf("the version '%s' is not a number", params[1]))
+		return
+	}
+	versions, err := bc.Repo.GetTemplateHistory(m, tpl.Name)
+	if err != nil {
+		bc.Logf(ERROR, m, "Error loading template history: %s", err.Error())
+		bc.Bot.SendSilent(bc, Recipient(m), "There has been an error loading the previous versions of your template.")
+		return
+	}
+	for _, v := range versions {
+		if v.Id != versionId {
+			continue
+		}
+		err = bc.Repo.UpdateTemplate(m.Chat.ID, tpl.Name, v.Template)
+		if err != nil {
+			bc.Logf(ERROR, m, "Restoring template failed: %s", err.Error())
+			bc.Bot.SendSilent(bc, Recipient(m), "Something went wrong while restoring your template.")
+			return
+		}
+		bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Successfully restored version %d of your template '%s'. The replaced version has been added to '/t history %s'.", versionId, tpl.Name, tpl.Name))
+		return
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("There is no version %d of your template '%s'. Please check '/t history %s'.", versionId, tpl.Name, tpl.Name))
+}
+
+type TemplateTx struct {
+}
+
+func (bc *BotController) templatesUse(m *tb.Message, params []string, isQuoted []bool) error {
+	if len(params) < 1 {
+		bc.templatesPicker(m)
 		return nil
 	}
+	name := params[0]
+	named, positional, date := splitTemplateArgs(params[1:], isQuoted[1:])
 	res, err := bc.Repo.GetTemplates(m, name)
 	if err != nil {
 		bc.Logf(ERROR, m, "Getting template to create tx failed: %s", err.Error())
@@ -164,12 +601,29 @@ func (bc *BotController) templatesUse(m *tb.Message, params ...string) error {
 		return fmt.Errorf("could not find the template you specified. Please create it first")
 	}
 	tpl := res[0]
-	tx, err := bc.State.TemplateTx(m, tpl.Template, bc.Repo.UserGetCurrency(m), date)
+	tx, err := bc.State.TemplateTx(m, tpl.Template, bc.Repo.UserGetCurrency(m), date, bc.Repo.UserGetTzOffset(m))
 	if err != nil {
 		bc.Logf(ERROR, m, "Creating tx from template failed: %s", err.Error())
+		bc.State.Clear(m)
 		return fmt.Errorf("something went wrong creating a transaction from your template: %s", err.Error())
 	}
-	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Creating a new transaction from your template '%s'.", tpl.Name))
+	bc.applyUserPreferences(m, tx)
+	if len(named) > 0 || len(positional) > 0 {
+		registry, err := bc.Repo.GetAccounts(m)
+		if err != nil {
+			bc.Logf(ERROR, m, "Could not get account registry: %s", err.Error())
+		}
+		if err := tx.Prefill(named, positional, registry); err != nil {
+			bc.Logf(INFO, m, "Filling template from arguments failed: %s", err.Error())
+			bc.State.Clear(m)
+			return fmt.Errorf("the transaction from your template '%s' could not be filled: %s", tpl.Name, err.Error())
+		}
+	}
+	if err := bc.Repo.RecordTemplateUsage(m.Chat.ID, tpl.Name); err != nil {
+		bc.Logf(ERROR, m, "Could not record template usage: %s", err.Error())
+		// Don't return, the transaction can be created anyway
+	}
+	bc.Bot.SendSilent(bc, Recipient(m), fmt.Sprintf("Creating a new transaction from your template '%s'.", tpl.Name), clearKeyboard())
 	if tx.IsDone() {
 		bc.finishTransaction(m, tx)
 		return nil
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
@@ -36,6 +37,10 @@ func TestTemplateHelpForNonexistingTemplate(t *testing.T) {
 	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t add nonesense"}})
 	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Blah"}})
 
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "category", "usageCount" FROM "bot::template"`)).
+		WithArgs(12345).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category", "usageCount"}))
 	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t"}})
 	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Usage help for /template", "send help for invalid command")
 
@@ -114,6 +119,10 @@ func TestTemplateAdd(t *testing.T) {
 	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "parameter count mismatch", "parameter count mismatch response")
 
 	// Step 1: Start template creation
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "myTemplate%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("myTemplateOld", "template data"))
 	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t add myTemplate"}})
 	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please provide a full transaction template", "template creation process response")
 	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_TPL, "state should show template process")
@@ -133,6 +142,272 @@ func TestTemplateAdd(t *testing.T) {
 	}
 }
 
+func TestTemplateAddInvalidExpression(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "vat%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t add vat"}})
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Invoice\"\n  Liabilities:VAT ${amount*vat}"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: invalid expression 'amount*vat'", "invalid expression should be rejected")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_TPL, "corrected template should be accepted afterwards")
+
+	template := "${date} * \"Invoice\"\n  Liabilities:VAT ${amount*19%}"
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::template" ("tgChatId", "name", "template") VALUES ($1, $2, $3)`)).
+		WithArgs(12345, "vat", template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: template}})
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_NONE, "state should be clean again")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplateAddValidation(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "rent%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t add rent"}})
+
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\"\n  Assets:Checking ${-amount\n  Expenses:Rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: the variable '${-amount' is not closed with '}'", "unclosed variable")
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\"\n  ${acount:from} ${-amount}\n  Expenses:Rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: the variable '${acount:from}' has the unknown type 'acount'", "unknown variable type")
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\" ${}\n  Assets:Checking ${-amount}\n  Expenses:Rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: the template contains an empty variable", "empty variable")
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\"\n  ${meta:Receipt}\n  Assets:Checking ${-amount}\n  Expenses:Rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: the metadata key 'Receipt' of the variable '${meta:Receipt}' is invalid", "uppercase metadata key")
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\"\n  ${meta:my key}\n  Assets:Checking ${-amount}\n  Expenses:Rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be saved: the metadata key 'my key' of the variable '${meta:my key}' is invalid", "metadata key with space")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_TPL, "corrected template should be accepted afterwards")
+
+	template := "${date} * \"${description?}\"\n  ${account:from} ${-amount}\n  Expenses:Rent ${amount:extra:for *extra costs*:USD}\n  Expenses:Rent {10 EUR}"
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::template" ("tgChatId", "name", "template") VALUES ($1, $2, $3)`)).
+		WithArgs(12345, "rent", template).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: template}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), `Using it, you will be asked for:
+1. amount
+2. amount for extra costs (in USD)
+3. description (optional)
+4. account from`, "prompts of the template")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_NONE, "state should be clean again")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplatePreview(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "shop%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("shopping", `2022-04-11 * "${payee?}" "${description}"
+  ${account:from} ${-amount}
+  Expenses:Shopping ${amount/2}
+  Expenses:Gifts ${amount/2}`))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t preview shop"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), `2022-04-11 * "Sample payee" "Sample description"
+  Assets:Sample:From                          -12.34 EUR
+  Expenses:Shopping                             6.17 EUR
+  Expenses:Gifts                                6.17 EUR
+`, "template filled with sample values")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "3. description", "prompts of the template")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_NONE, "preview should not start a transaction")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplateAddExistingName(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "rent%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("rent", "template data"))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t add rent"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "There already is a template called 'rent'", "existing name should be rejected")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "/t edit rent", "edit should be suggested")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_NONE, "no template creation should be started")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplateEdit(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	oldTemplate := "${date} * \"Rent\"\n  Assets:Checking -800.00 EUR\n  Expenses:Rent"
+	newTemplate := "${date} * \"Rent\"\n  Assets:Checking ${-amount}\n  Expenses:Rent"
+	getTemplate := func() {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+			WithArgs(12345, "re%").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("rent", newTemplate))
+	}
+
+	// Edit and cancel
+	getTemplate()
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t edit re"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please provide the new version of your template 'rent'", "edit should ask for new version")
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), newTemplate, "edit should show current version")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_TPL, "state should show template process")
+	bc.commandCancel(&MockContext{M: &tb.Message{Chat: chat, Text: "/cancel"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "template edit has been cancelled", "cancel message")
+
+	// Edit
+	getTemplate()
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t edit re"}})
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "${date} * \"Rent\"\n  Assets:Checking ${-amount"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "/cancel the edit", "invalid template should be rejected")
+
+	mock.ExpectExec(`UPDATE "bot::template" SET "template" = \$3`).
+		WithArgs(12345, "rent", oldTemplate).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: oldTemplate}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully updated your template 'rent'", "template should be updated")
+	helpers.TestExpect(t, bc.State.states[chatId(chat.ID)], ST_NONE, "state should be clean again")
+
+	// History
+	replaced := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	history := func() {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "id", "template", "replaced" FROM "bot::templateHistory"`)).
+			WithArgs(12345, "rent").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "template", "replaced"}).AddRow(7, newTemplate, replaced))
+	}
+	getTemplate()
+	history()
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t history re"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Version 7 (replaced 2026-10-17 10:00):\n"+newTemplate, "history should list previous version")
+
+	// Restore
+	getTemplate()
+	history()
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t restore re 8"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "There is no version 8", "unknown version")
+
+	getTemplate()
+	history()
+	mock.ExpectExec(`UPDATE "bot::template" SET "template" = \$3`).
+		WithArgs(12345, "rent", newTemplate).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t restore re 7"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully restored version 7 of your template 'rent'", "version should be restored")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplateRenameAndShow(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	getTemplate := func(name string, rows *sqlmock.Rows) {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+			WithArgs(12345, name+"%").
+			WillReturnRows(rows)
+	}
+	rentRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"name", "template"}).AddRow("rent", "template data")
+	}
+
+	getTemplate("rent", rentRows())
+	getTemplate("housing", sqlmock.NewRows([]string{"name", "template"}).AddRow("housing", "other template"))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t rename rent housing"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "There already is a template called 'housing'", "existing name should be rejected")
+
+	getTemplate("rent", rentRows())
+	getTemplate("home", sqlmock.NewRows([]string{"name", "template"}).AddRow("homeOffice", "other template"))
+	mock.ExpectExec(`UPDATE "bot::template" SET "name" = \$3`).
+		WithArgs(12345, "rent", "home").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t rename rent home"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Successfully renamed your template 'rent' to 'home'", "template should be renamed")
+
+	getTemplate("home", sqlmock.NewRows([]string{"name", "template"}).AddRow("home", "template data").AddRow("homeOffice", "other template"))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t show home"}})
+	helpers.TestExpect(t, fmt.Sprintf("%v", bot.LastSentWhat), "home:\ntemplate data", "template should be shown")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestTemplateRm(t *testing.T) {
 	// test dependencies
 	crud.TEST_MODE = true
@@ -180,6 +455,14 @@ func TestTemplateUse(t *testing.T) {
 		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
 		WithArgs(chat.ID, helpers.USERSET_CUR).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("TEST_CURRENCY"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.
+		ExpectExec(regexp.QuoteMeta(`UPDATE "bot::template" SET "usageCount" = "usageCount" + 1`)).
+		WithArgs(chat.ID, "test").
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t test 2022-04-11"}})
 	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.AllLastSentWhat[len(bot.AllLastSentWhat)-2]), "Creating a new transaction from your template 'test'", "template tx starting msg")
 	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "amount", "asking for amount")
@@ -197,13 +480,13 @@ func TestTemplateUse(t *testing.T) {
 		WithArgs(chat.ID, helpers.USERSET_TZOFF).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}))
 	mock.
-		ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::transaction" ("tgChatId", "value")
-		VALUES ($1, $2);`)).
+		ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::transaction" ("tgChatId", "value", "txData")
+		VALUES ($1, $2, $3);`)).
 		WithArgs(chat.ID, `2022-04-11 * "Test" "Buy something"
   fromFix                                     -10.51 EUR_TEST
-  toFix1                                        5.255 EUR_TEST
-  toFix2                                        5.255 EUR_TEST
-`).
+  toFix1                                        5.26 EUR_TEST
+  toFix2                                        5.25 EUR_TEST
+`, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	tx := bc.State.txStates[chatId(chat.ID)]
 	tx.Input(&tb.Message{Text: "10.51 EUR_TEST"})                                       // amount
@@ -213,3 +496,160 @@ func TestTemplateUse(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestSplitTemplateArgs(t *testing.T) {
+	named, positional, date := splitTemplateArgs([]string{"3.50", "Bakery", "2026-10-16"}, nil)
+	helpers.TestExpect(t, len(named), 0, "named args")
+	helpers.TestExpect(t, strings.Join(positional, "|"), "3.50|Bakery", "positional args")
+	helpers.TestExpect(t, date, "2026-10-16", "date")
+
+	named, positional, date = splitTemplateArgs([]string{"amount=3.5", "Coffee", "last", "friday"}, nil)
+	helpers.TestExpect(t, len(named), 1, "named args")
+	helpers.TestExpect(t, named[0].Key+"="+named[0].Value, "amount=3.5", "named arg")
+	helpers.TestExpect(t, strings.Join(positional, "|"), "Coffee", "positional args")
+	helpers.TestExpect(t, date, "last friday", "relative date")
+
+	// Digits only are taken as values
+	named, positional, date = splitTemplateArgs([]string{"account:from=Assets:Cash", "11", "date=-2"}, nil)
+	helpers.TestExpect(t, named[0].Key, "account:from", "named arg with specifier")
+	helpers.TestExpect(t, strings.Join(positional, "|"), "11", "positional args")
+	helpers.TestExpect(t, date, "-2", "named date")
+
+	// Quoted arguments are never taken as date
+	for _, command := range []string{`12 "Friday"`, `12 "Last Monday"`, `12 Lunch "2026-10-16"`} {
+		args, isQuoted := helpers.SplitQuotedCommandTokens(command)
+		_, positional, date = splitTemplateArgs(args, isQuoted)
+		helpers.TestExpect(t, len(positional), len(args), "quoted arguments should be kept as values: "+command)
+		helpers.TestExpect(t, date, "", "quoted arguments should not be taken as date: "+command)
+	}
+	args, isQuoted := helpers.SplitQuotedCommandTokens(`12 "Last Monday" yesterday`)
+	_, positional, date = splitTemplateArgs(args, isQuoted)
+	helpers.TestExpect(t, strings.Join(positional, "|"), "12|Last Monday", "positional args before date")
+	helpers.TestExpect(t, date, "yesterday", "unquoted date after quoted argument")
+}
+
+func TestTemplateUseWithArgs(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	startTemplate := func() {
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+			WithArgs(12345, "coffee%").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("coffee", `${date} * "${payee?}" "${description}"
+  Assets:Cash ${-amount}
+  ${account:to}`))
+		mock.
+			ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+			WithArgs(chat.ID, helpers.USERSET_CUR).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+		mock.
+			ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+			WithArgs(chat.ID, helpers.USERSET_TZOFF).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}))
+		mock.
+			ExpectQuery(regexp.QuoteMeta(`SELECT "account" FROM "bot::account"`)).
+			WithArgs(chat.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"account"}).AddRow("Assets:Cash").AddRow("Expenses:Coffee"))
+	}
+
+	startTemplate()
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: `/t coffee 3.50 Bakery account:to=Expenses:Cake 2026-10-16`}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "could not be filled: the account 'Expenses:Cake' is not open in your registry", "account not in registry")
+	helpers.TestExpect(t, bc.State.GetType(&tb.Message{Chat: chat}), ST_NONE, "no transaction should be left open")
+
+	startTemplate()
+	mock.
+		ExpectExec(regexp.QuoteMeta(`UPDATE "bot::template" SET "usageCount" = "usageCount" + 1`)).
+		WithArgs(chat.ID, "coffee").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: `/t coffee 3.50 "Bakery Miller" account:to=Expenses:Coffee 2026-10-16`}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Please enter a *description*", "remaining field should be asked for")
+
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_CUR).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("EUR"))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TAG).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.
+		ExpectQuery(`SELECT "value" FROM "bot::userSetting"`).
+		WithArgs(chat.ID, helpers.USERSET_TZOFF).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.
+		ExpectExec(regexp.QuoteMeta(`INSERT INTO "bot::transaction" ("tgChatId", "value", "txData")
+		VALUES ($1, $2, $3);`)).
+		WithArgs(chat.ID, `2026-10-16 * "Bakery Miller" "Coffee and cake"
+  Assets:Cash                                  -3.50 EUR
+  Expenses:Coffee
+`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.handleTextState(&MockContext{M: &tb.Message{Chat: chat, Text: "Coffee and cake"}})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTemplatePicker(t *testing.T) {
+	// test dependencies
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bc := NewBotController(db)
+	bot := &MockBot{}
+	bc.AddBotAndStart(bot)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "template" FROM "bot::template" WHERE "tgChatId" = $1 AND "name" LIKE $2`)).
+		WithArgs(12345, "cof%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "template"}).AddRow("coffee", "template data"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "bot::template" SET "category" = $3`)).
+		WithArgs(12345, "coffee", "Food").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t category cof Food"}})
+	helpers.TestExpect(t, fmt.Sprintf("%v", bot.LastSentWhat), "Your template 'coffee' is now listed in the category 'Food'.", "category set")
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "name", "category", "usageCount" FROM "bot::template"`)).
+		WithArgs(12345).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category", "usageCount"}).
+			AddRow("rent", "", 12).
+			AddRow("bus", "Transport", 10).
+			AddRow("coffee", "Food", 8).
+			AddRow("lunch", "Food", 5).
+			AddRow("my snack", "Food", 3).
+			AddRow("dinner", "Food", 1))
+	bc.commandTemplates(&MockContext{M: &tb.Message{Chat: chat, Text: "/t"}})
+	helpers.TestStringContains(t, fmt.Sprintf("%v", bot.LastSentWhat), "Food: coffee, lunch, my snack, dinner\nTransport: bus\nOther: rent", "templates by category")
+	keyboard := bot.LastSentOptions[0].(*tb.ReplyMarkup)
+	rows := []string{}
+	for _, row := range keyboard.ReplyKeyboard {
+		buttons := []string{}
+		for _, button := range row {
+			buttons = append(buttons, button.Text)
+		}
+		rows = append(rows, strings.Join(buttons, " | "))
+	}
+	helpers.TestExpect(t, strings.Join(rows, "\n"), `/t coffee | /t lunch | /t "my snack"
+/t dinner
+/t bus
+/t rent`, "picker keyboard")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,9 +1,10 @@
 package bot
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
-	"math"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,7 +30,27 @@ type Input struct {
 }
 
 func HandleFloat(m *tb.Message) (string, error) {
-	input := strings.TrimSpace(m.Text)
+	return handleAmount(m, false, nil)
+}
+
+// HandleBalanceAmount handles the amounts of balance assertions. Other than posting amounts, these keep their sign and can't carry a price annotation.
+func HandleBalanceAmount(m *tb.Message) (string, error) {
+	return handleBalanceAmount(m, nil)
+}
+
+func handleBalanceAmount(m *tb.Message, locale *c.Locale) (string, error) {
+	if strings.ContainsAny(m.Text, "@{") {
+		return "", fmt.Errorf("balance assertions can't carry a price or cost annotation")
+	}
+	return handleAmount(m, true, locale)
+}
+
+// handleAmount parses an amount entered in the locale (or with guessed separators, if nil) into its canonical form
+func handleAmount(m *tb.Message, keepSign bool, locale *c.Locale) (string, error) {
+	input, annotation, err := splitPriceAnnotation(strings.TrimSpace(m.Text), locale)
+	if err != nil {
+		return "", err
+	}
 	split := strings.Split(input, " ")
 	var (
 		value    = split[0]
@@ -39,58 +60,115 @@ func HandleFloat(m *tb.Message) (string, error) {
 		return "", fmt.Errorf("input '%s' contained too many spaces. It should only contain the value and an optional currency", input)
 	}
 	if len(split) == 2 {
-		currency = " " + split[1]
+		currency = split[1]
 	}
 	// Should fail if tx is left open (with trailing '+' operator) and currency is given
-	if strings.HasSuffix(value, "+") && currency != "" {
+	if strings.HasSuffix(value, "+") && (currency != "" || annotation != nil) {
 		return "", fmt.Errorf("for transactions being kept open with trailing '+' operator, no additionally specified currency is allowed")
 	}
-	operator := ""
-	amounts := []string{value}
-	if strings.Contains(value, "+") {
-		amounts = strings.Split(value, "+")
-		operator = "+"
-	} else if strings.Contains(value, "*") {
-		amounts = strings.Split(value, "*")
-		operator = "*"
-		if len(amounts) != 2 {
-			return "", fmt.Errorf("expected exactly two multiplicators ('a*b')")
-		}
-	}
-	values := []float64{}
-	for _, amount := range amounts {
-		value, err := handleThousandsSeparators(amount)
-		if err != nil {
+	if currency != "" {
+		if err := c.IsValidCommodity(currency); err != nil {
 			return "", err
 		}
-		v, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			return "", fmt.Errorf("parsing failed at value '%s': %s", value, err.Error())
-		}
-		if v < 0 {
-			c.LogLocalf(INFO, nil, "Got negative value. Inverting.")
-			v *= -1
-		}
-		c.LogLocalf(TRACE, nil, "Handled float: '%s' -> %f", amount, v)
-		values = append(values, v)
 	}
-	finalAmount := 0.0
-	if operator == "+" {
-		sum := 0.0
-		for _, v := range values {
-			sum += v
+	finalAmount, err := evaluateExpression(value, locale)
+	if err != nil {
+		return "", err
+	}
+	if finalAmount.Sign() < 0 && !keepSign {
+		c.LogLocalf(INFO, nil, "Got negative value. Inverting.")
+		finalAmount = finalAmount.Abs()
+	}
+	c.LogLocalf(TRACE, nil, "Handled float: '%s' -> %s", value, finalAmount)
+	annotationS := ""
+	if annotation != nil {
+		if err := c.IsValidCommodity(annotation.Currency); err != nil {
+			return "", err
 		}
-		finalAmount = sum
-	} else if operator == "*" {
-		product := 1.0
-		for _, v := range values {
-			product *= v
+		annotationS = " " + annotation.String()
+	}
+	return FORMATTER_PLACEHOLDER + strings.TrimSpace(ParseAmount(finalAmount, currency)+" "+currency) + annotationS, nil
+}
+
+const (
+	PRICE_PER_UNIT = "@"
+	PRICE_TOTAL    = "@@"
+	PRICE_COST     = "{}"
+)
+
+// PriceAnnotation is the price ('@' / '@@') or cost ('{}') part following the units of a posting amount
+type PriceAnnotation struct {
+	Operator string
+	Amount   c.Decimal
+	Currency string
+}
+
+func isPriceAnnotation(s string) bool {
+	return strings.HasPrefix(s, "@") || strings.HasPrefix(s, "{")
+}
+
+func splitPriceAnnotation(input string, locale *c.Locale) (units string, annotation *PriceAnnotation, err error) {
+	idx := strings.IndexAny(input, "@{")
+	if idx < 0 {
+		return input, nil, nil
+	}
+	annotation, err = parsePriceAnnotation(input[idx:], locale)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(input[:idx]), annotation, nil
+}
+
+func ParsePriceAnnotation(s string) (*PriceAnnotation, error) {
+	return parsePriceAnnotation(s, nil)
+}
+
+func parsePriceAnnotation(s string, locale *c.Locale) (*PriceAnnotation, error) {
+	s = strings.TrimSpace(s)
+	p := &PriceAnnotation{}
+	var inner string
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("cost annotation '%s' is missing its closing '}'", s)
 		}
-		finalAmount = product
+		p.Operator = PRICE_COST
+		inner = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	} else if strings.HasPrefix(s, PRICE_TOTAL) {
+		p.Operator = PRICE_TOTAL
+		inner = strings.TrimPrefix(s, PRICE_TOTAL)
+	} else if strings.HasPrefix(s, PRICE_PER_UNIT) {
+		p.Operator = PRICE_PER_UNIT
+		inner = strings.TrimPrefix(s, PRICE_PER_UNIT)
 	} else {
-		finalAmount = values[0]
+		return nil, fmt.Errorf("'%s' is not a price or cost annotation", s)
+	}
+	fields := strings.Fields(inner)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("annotation '%s' should consist of exactly one number and its currency, e.g. '@ 0.92 EUR', '@@ 38.73 EUR' or '{210.55 USD}'", s)
+	}
+	value, err := parseNumber(fields[0], locale)
+	if err != nil {
+		return nil, err
+	}
+	p.Amount, err = c.ParseDecimal(value)
+	if err != nil {
+		return nil, fmt.Errorf("parsing failed at annotation value '%s': %s", fields[0], err.Error())
+	}
+	if p.Amount.Sign() <= 0 {
+		return nil, fmt.Errorf("annotation value '%s' must be positive", fields[0])
+	}
+	p.Currency = fields[1]
+	if strings.ContainsAny(p.Currency, "@{}") {
+		return nil, fmt.Errorf("invalid annotation currency '%s'", p.Currency)
+	}
+	return p, nil
+}
+
+func (p *PriceAnnotation) String() string {
+	if p.Operator == PRICE_COST {
+		return fmt.Sprintf("{%s %s}", ParseAmount(p.Amount, p.Currency), p.Currency)
 	}
-	return FORMATTER_PLACEHOLDER + ParseAmount(finalAmount) + currency, nil
+	return fmt.Sprintf("%s %s %s", p.Operator, ParseAmount(p.Amount, p.Currency), p.Currency)
 }
 
 func handleThousandsSeparators(value string) (cleanValue string, err error) {
@@ -128,29 +206,111 @@ func HandleRaw(m *tb.Message) (string, error) {
 	return m.Text, nil
 }
 
-func ParseDate(m string) (string, error) {
-	// TODO: Handle tz offset
-	today := time.Now()
-	patterns := []string{
-		"2006-01-02",
-		"20060102",
-		"01-02",
-		"0102",
-		"02",
+// Flags of transactions, marking them as completed or pending (e.g. if the amount is not final yet)
+const (
+	FLAG_COMPLETED = "*"
+	FLAG_PENDING   = "!"
+)
+
+func HandleFlag(m *tb.Message) (string, error) {
+	flag := strings.TrimSpace(m.Text)
+	if flag != FLAG_COMPLETED && flag != FLAG_PENDING {
+		return "", fmt.Errorf("the flag '%s' is neither '%s' (completed) nor '%s' (pending)", flag, FLAG_COMPLETED, FLAG_PENDING)
+	}
+	return flag, nil
+}
+
+func HandleAccount(m *tb.Message) (string, error) {
+	account := strings.TrimSpace(m.Text)
+	if err := c.IsValidAccount(account); err != nil {
+		return "", err
+	}
+	return account, nil
+}
+
+var WEEKDAYS = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Today returns the current date for the given timezone offset (in hours)
+func Today(tzOffset int) time.Time {
+	now := time.Now().UTC().Add(time.Duration(tzOffset) * time.Hour)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseRelativeDate handles 'today', 'yesterday', '-<days>', '<weekday>' (most recent one, including today) and 'last <weekday>' (most recent one before today)
+func parseRelativeDate(m string, today time.Time) (time.Time, bool) {
+	switch m {
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
 	}
-	for _, p := range patterns {
-		t, err := time.Parse(p, m)
+	if strings.HasPrefix(m, "-") {
+		days, err := strconv.Atoi(strings.TrimPrefix(m, "-"))
+		if err != nil || days < 0 {
+			return time.Time{}, false
+		}
+		return today.AddDate(0, 0, -days), true
+	}
+	weekdayInput := strings.TrimSpace(strings.TrimPrefix(m, "last "))
+	weekday, isWeekday := WEEKDAYS[weekdayInput]
+	if !isWeekday {
+		return time.Time{}, false
+	}
+	daysBack := (int(today.Weekday()) - int(weekday) + 7) % 7
+	if daysBack == 0 && weekdayInput != m {
+		daysBack = 7
+	}
+	return today.AddDate(0, 0, -daysBack), true
+}
+
+// ParseDate parses absolute and relative date inputs relative to the user's current date.
+// Dates without year (or month) are never placed in the future, but in the most recent matching year (or month) instead.
+func ParseDate(m string, tzOffset int) (string, error) {
+	return ParseDateRelativeTo(m, Today(tzOffset))
+}
+
+func ParseDateRelativeTo(m string, today time.Time) (string, error) {
+	input := strings.ToLower(strings.TrimSpace(m))
+	if t, isRelative := parseRelativeDate(input, today); isRelative {
+		return t.Format(c.BEANCOUNT_DATE_FORMAT), nil
+	}
+	for _, p := range []string{"2006-01-02", "20060102"} {
+		t, err := time.Parse(p, input)
 		if err == nil {
-			if len(m) < len("20060102") {
-				t, _ = time.Parse(c.BEANCOUNT_DATE_FORMAT, fmt.Sprintf("%s-%s", today.Format("2006"), t.Format("01-02")))
+			return t.Format(c.BEANCOUNT_DATE_FORMAT), nil
+		}
+	}
+	for _, p := range []string{"01-02", "0102"} {
+		t, err := time.Parse(p, input)
+		if err != nil {
+			continue
+		}
+		// Going back up to 8 years finds the most recent 02-29
+		for year := today.Year(); year >= today.Year()-8; year-- {
+			d := time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			if d.Day() == t.Day() && !d.After(today) {
+				return d.Format(c.BEANCOUNT_DATE_FORMAT), nil
 			}
-			if len(m) < len("0102") {
-				t, _ = time.Parse(c.BEANCOUNT_DATE_FORMAT, fmt.Sprintf("%s-%s", today.Format("2006-01"), t.Format("02")))
+		}
+	}
+	if t, err := time.Parse("02", input); err == nil {
+		for monthsBack := 0; monthsBack <= 2; monthsBack++ {
+			d := time.Date(today.Year(), today.Month()-time.Month(monthsBack), t.Day(), 0, 0, 0, 0, time.UTC)
+			if d.Day() == t.Day() && !d.After(today) {
+				return d.Format(c.BEANCOUNT_DATE_FORMAT), nil
 			}
-			return t.Format(c.BEANCOUNT_DATE_FORMAT), nil
 		}
 	}
-	return "", fmt.Errorf("Input could not be parsed to a specific date. Multiple date formats are allowed, e.g. YYYY-MM-DD, MM-DD or DD")
+	return "", fmt.Errorf("Input could not be parsed to a specific date. Multiple date formats are allowed, e.g. YYYY-MM-DD, MM-DD or DD, " +
+		"as well as relative dates like 'today', 'yesterday', '-2' (days ago), 'fri' or 'last friday'")
 }
 
 type Tx interface {
@@ -159,19 +319,29 @@ type Tx interface {
 	IsDone() bool
 	Debug() string
 	NextHint(*crud.Repo, *tb.Message) *Hint
+	NextField() *TemplateField
 	EnrichHint(r *crud.Repo, m *tb.Message, i *Input) *Hint
 	FillTemplate(currency, tag string, tzOffset int) (string, error)
 	CacheData() map[string]string
+	Serialize() (string, error)
+	Back() error
 
-	SetDate(string) (Tx, error)
+	SetDate(d string, tzOffset int) (Tx, error)
+	SetLocale(*c.Locale)
+	SetCommodities([]string)
+	Prefill(named []*TemplateArg, positional []string, registry []string) error
 	setTimeIfEmpty(tzOffset int) bool
 }
 
 type SimpleTx struct {
 	template               string
 	userCurrencySuggestion string
+	locale                 *c.Locale // separators of amounts entered by the user, guessed if nil
+	commodities            []string  // currencies accepted besides the suggested one, any if empty
+	selectedCurrency       string    // currency selected for the amount asked for next
 
 	nextFields []*TemplateField
+	history    []*TemplateField // fields already answered by the user, in order
 	data       map[string]string
 }
 
@@ -181,7 +351,7 @@ type TemplateHintData struct {
 	FieldName      string
 	FieldSpecifier string
 	FieldHint      string
-	FieldDefault   string // This is not filled by field parsing logic yet. Instead values can be passed in.
+	FieldDefault   string // Currency suggested for amount fields: the field's own one or the user's default currency
 }
 
 type Type string
@@ -197,24 +367,82 @@ var TEMPLATE_TYPE_HINTS = map[Type]HintTemplate{
 	},
 	Type(c.FIELD_ACCOUNT): {
 		Text:    "Please enter the *account* {{.FieldHint}} (or select one from the list)",
-		Handler: HandleRaw,
+		Handler: HandleAccount,
 	},
 	Type(c.FIELD_DESCRIPTION): {
 		Text:    "Please enter a *description* {{.FieldHint}} (or select one from the list)",
 		Handler: HandleRaw,
 	},
+	Type(c.FIELD_PAYEE): {
+		Text:    "Please enter the *payee* {{.FieldHint}} (or select one from the list)",
+		Handler: HandleRaw,
+	},
+	Type(c.FIELD_META): {
+		Text:    "Please enter the value of the metadata {{.FieldHint}} (or select one from the list)",
+		Handler: HandleRaw,
+	},
+	Type(c.FIELD_POSTING): {
+		Text:    "Would you like to *add another posting*, e.g. to split the amount onto several accounts? Otherwise select *Done* to finish the transaction.",
+		Handler: HandlePosting,
+	},
+	Type(c.FIELD_FLAG): {
+		Text:    "Please select the *flag* of the transaction {{.FieldHint}} ('*' for completed, '!' for pending)",
+		Handler: HandleFlag,
+	},
 }
 
-const TEMPLATE_SIMPLE_DEFAULT = `${date} * "${description}"${tag}
+const TEMPLATE_SIMPLE_DEFAULT = `${date} ${flag} "${description}"${tag}
   ${account:from:the money came *from*} ${-amount}
-  ${account:to:the money went *to*}`
+  ${account:to:the money went *to*}${posting}`
+
+const TEMPLATE_BALANCE = `${date} balance ${account:balance:to assert the balance *of*} ${amount:balance:the account holds}`
 
 func CreateSimpleTx(suggestedCur, template string) (Tx, error) {
-	tx := (&SimpleTx{
-		data:                   make(map[string]string),
+	return createSimpleTxWithData(suggestedCur, template, make(map[string]string)), nil
+}
+
+func createSimpleTxWithData(suggestedCur, template string, data map[string]string) Tx {
+	if (template == TEMPLATE_SIMPLE_DEFAULT || template == TEMPLATE_SPLIT) && data[c.FqCacheKey(c.FIELD_FLAG)] == "" {
+		// Simple transactions are completed, unless marked as pending explicitly
+		data[c.FqCacheKey(c.FIELD_FLAG)] = FLAG_COMPLETED
+	}
+	return (&SimpleTx{
+		data:                   data,
 		template:               template,
 		userCurrencySuggestion: suggestedCur,
 	}).Prepare()
+}
+
+// SimpleTxData is the persisted form of a SimpleTx, allowing to restore it for editing
+type SimpleTxData struct {
+	Template string            `json:"template"`
+	Data     map[string]string `json:"data"`
+}
+
+func (tx *SimpleTx) Serialize() (string, error) {
+	serialized, err := json.Marshal(&SimpleTxData{Template: tx.template, Data: tx.data})
+	if err != nil {
+		return "", err
+	}
+	return string(serialized), nil
+}
+
+// RestoreSimpleTx recreates a (completely filled) SimpleTx from its serialized form
+func RestoreSimpleTx(serialized, suggestedCur string) (*SimpleTx, error) {
+	txData := &SimpleTxData{}
+	err := json.Unmarshal([]byte(serialized), txData)
+	if err != nil {
+		return nil, fmt.Errorf("could not restore transaction data: %s", err.Error())
+	}
+	if txData.Data == nil {
+		txData.Data = make(map[string]string)
+	}
+	tx := &SimpleTx{
+		data:                   txData.Data,
+		template:               txData.Template,
+		userCurrencySuggestion: suggestedCur,
+	}
+	tx.Prepare()
 	return tx, nil
 }
 
@@ -230,11 +458,23 @@ func (tx *SimpleTx) CacheData() (data map[string]string) {
 	}
 	cleanedData := make(map[string]string)
 	for k, d := range tx.data {
-		if !c.ArrayContains(fieldOrder, k) {
+		if isAdditionalPosting(ParseTemplateField(k, "")) {
+			// Suggestions for additional postings are taken from the last posting
+			continue
+		}
+		if !c.ArrayContains(fieldOrder, k) || d == "" {
+			// Skipped optional fields are not cached
 			continue
 		}
 		cleanedData[k] = strings.ReplaceAll(d, FORMATTER_PLACEHOLDER, "")
 	}
+	if currency := tx.amountCurrency(); currency != "" {
+		cleanedData[c.FqCacheKey(c.FIELD_CURRENCY)] = currency
+	}
+	payee, description := cleanedData[c.FqCacheKey(c.FIELD_PAYEE)], cleanedData[c.FqCacheKey(c.FIELD_DESCRIPTION)]
+	if payee != "" && description != "" {
+		cleanedData[payeeDescriptionCacheKey(payee)] = description
+	}
 	log.Print(cleanedData)
 	return cleanedData
 }
@@ -245,8 +485,15 @@ func (tx *SimpleTx) Prepare() Tx {
 	return tx
 }
 
-func (tx *SimpleTx) SetDate(d string) (Tx, error) {
-	date, err := ParseDate(d)
+// SetLocale sets the locale amounts are entered and shown in
+func (tx *SimpleTx) SetLocale(locale *c.Locale) {
+	tx.locale = locale
+}
+
+// SetDate sets the date of the transaction. Relative dates should be resolved with the user's timezone offset beforehand.
+// SetDate sets the date of the transaction. Relative dates are resolved in the user's timezone.
+func (tx *SimpleTx) SetDate(d string, tzOffset int) (Tx, error) {
+	date, err := ParseDate(d, tzOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -257,8 +504,7 @@ func (tx *SimpleTx) SetDate(d string) (Tx, error) {
 func (tx *SimpleTx) setTimeIfEmpty(tzOffset int) bool {
 	if tx.data[c.FqCacheKey(c.FIELD_DATE)] == "" {
 		// set today as fallback/default date
-		timezoneOff := time.Duration(tzOffset) * time.Hour
-		tx.data[c.FqCacheKey(c.FIELD_DATE)] = time.Now().UTC().Add(timezoneOff).Format(c.BEANCOUNT_DATE_FORMAT)
+		tx.data[c.FqCacheKey(c.FIELD_DATE)] = Today(tzOffset).Format(c.BEANCOUNT_DATE_FORMAT)
 		return true
 	}
 	return false
@@ -277,26 +523,25 @@ func (tx *SimpleTx) setTagIfEmpty(tag string) bool {
 }
 
 func SortTemplateFields(unsortedFields []*TemplateField) []*TemplateField {
+	const unknownFieldRank = 5 // e.g. flag or meta
 	sortMapping := map[string]int{
 		c.FIELD_AMOUNT:      1,
-		c.FIELD_DESCRIPTION: 2,
-		c.FIELD_ACCOUNT:     3,
+		c.FIELD_PAYEE:       2,
+		c.FIELD_DESCRIPTION: 3,
+		c.FIELD_ACCOUNT:     4,
+		c.FIELD_POSTING:     unknownFieldRank + 1, // after all other fields
 	}
-	sort.Slice(unsortedFields, func(i, j int) bool {
-		if unsortedFields[i].FieldName == unsortedFields[j].FieldName {
-			sortedSpecifiers := []string{unsortedFields[i].FieldSpecifier, unsortedFields[j].FieldSpecifier}
-			sort.Strings(sortedSpecifiers)
-			return unsortedFields[i].FieldSpecifier == sortedSpecifiers[0]
+	rank := func(f *TemplateField) int {
+		if r, exists := sortMapping[f.FieldName]; exists {
+			return r
 		}
-		a, exists := sortMapping[unsortedFields[i].FieldName]
-		if !exists {
-			a = len(sortMapping) + 1
-		}
-		b, exists := sortMapping[unsortedFields[j].FieldName]
-		if !exists {
-			b = len(sortMapping) + 1
+		return unknownFieldRank
+	}
+	sort.SliceStable(unsortedFields, func(i, j int) bool {
+		if unsortedFields[i].FieldName == unsortedFields[j].FieldName {
+			return unsortedFields[i].FieldSpecifier < unsortedFields[j].FieldSpecifier
 		}
-		return a < b
+		return rank(unsortedFields[i]) < rank(unsortedFields[j])
 	})
 	return unsortedFields
 }
@@ -314,14 +559,33 @@ func ParseTemplateFields(template, currencySuggestion string) []*TemplateField {
 	return SortTemplateFields(unsortedFields)
 }
 
+// SKIP_OPTIONAL is the input to leave an optional field (e.g. '${description?}') empty
+const SKIP_OPTIONAL = "skip"
+
 type NumberConfig struct {
 	Fraction   int
 	IsNegative bool
+	Currency   string // Currency of amounts entered without one, e.g. '${amount:received:the money *received*:USD}'
+	Expression string // Calculated from the entered amount, e.g. 'amount*19%' for '${amount*19%}'
+}
+
+var fractionPattern = regexp.MustCompile(`^\w+/\d+$`)
+
+// isAmountExpression tells whether a field calculates with the amount, e.g. 'amount*19%' or '(amount+2)*3'.
+// Expressions referring to anything but the amount and numbers are rejected when validating the template.
+func isAmountExpression(expression string) bool {
+	unsigned := strings.TrimLeft(expression, "-")
+	if unsigned == c.FIELD_AMOUNT || fractionPattern.MatchString(unsigned) {
+		// Plain (negative) amounts and fractions, e.g. '-amount' or 'amount/3'
+		return false
+	}
+	return strings.Contains(expression, c.FIELD_AMOUNT) && strings.ContainsAny(unsigned, "+-*/%()")
 }
 
 type TemplateField struct {
 	TemplateHintData
 	NumberConfig
+	IsOptional bool
 }
 
 func (tf *TemplateField) FieldIdentifierForValue() string {
@@ -335,6 +599,7 @@ func ParseTemplateField(rawField, currencySuggestion string) *TemplateField {
 			Raw: rawField,
 		},
 		NumberConfig{},
+		false,
 	}
 
 	splitFieldByColon := strings.Split(rawField, ":")
@@ -345,12 +610,29 @@ func ParseTemplateField(rawField, currencySuggestion string) *TemplateField {
 	if len(splitFieldByColon) >= 3 {
 		field.FieldHint = strings.TrimSpace(splitFieldByColon[2])
 	}
+	if len(splitFieldByColon) >= 4 {
+		field.Currency = strings.TrimSpace(splitFieldByColon[3])
+		if err := c.IsValidCommodity(field.Currency); err != nil {
+			c.LogLocalf(WARN, nil, "ignoring currency of template field: '%s' -> %s", rawField, err.Error())
+			field.Currency = ""
+		}
+	}
 	if field.FieldHint == "" && field.FieldSpecifier != "" {
 		field.FieldHint = fmt.Sprintf("*%s*", field.FieldSpecifier)
 	}
 
-	field.IsNegative = strings.HasPrefix(field.FieldName, "-")
-	field.FieldName = strings.TrimLeft(field.FieldName, "-")
+	field.IsOptional = strings.HasSuffix(field.FieldName, "?")
+	field.FieldName = strings.TrimSuffix(field.FieldName, "?")
+
+	// A leading '-' stays part of an expression, so that e.g. '-amount-1.50' is evaluated as written
+	expression := strings.ReplaceAll(field.FieldName, " ", "")
+	if isAmountExpression(expression) {
+		field.Expression = expression
+		field.FieldName = c.FIELD_AMOUNT
+	} else {
+		field.IsNegative = strings.HasPrefix(field.FieldName, "-")
+		field.FieldName = strings.TrimLeft(field.FieldName, "-")
+	}
 
 	fractionSplits := strings.Split(field.FieldName, "/")
 	field.FieldName = fractionSplits[0]
@@ -372,6 +654,9 @@ func ParseTemplateField(rawField, currencySuggestion string) *TemplateField {
 
 	if field.FieldName == c.FIELD_AMOUNT {
 		field.FieldDefault = currencySuggestion
+		if field.Currency != "" {
+			field.FieldDefault = field.Currency
+		}
 	}
 
 	return field
@@ -379,15 +664,82 @@ func ParseTemplateField(rawField, currencySuggestion string) *TemplateField {
 
 func (tx *SimpleTx) Input(m *tb.Message) (isDone bool, err error) {
 	nextField := tx.nextFields[0]
-	hint := TEMPLATE_TYPE_HINTS[Type(nextField.FieldName)]
-	res, err := hint.Handler(m)
-	if err != nil {
-		return tx.IsDone(), err
+	var res string
+	if nextField.IsOptional && strings.TrimSpace(m.Text) == SKIP_OPTIONAL {
+		res = ""
+	} else {
+		if nextField.FieldName == c.FIELD_AMOUNT {
+			if isSelection, err := tx.selectCurrency(nextField, m); isSelection {
+				return tx.IsDone(), err
+			}
+		}
+		res, err = tx.fieldHandler(nextField)(m)
+		if err != nil {
+			return tx.IsDone(), err
+		}
+	}
+	if nextField.FieldName == c.FIELD_POSTING && res == POSTING_ADD {
+		// Not answered yet, it is asked for again after the new posting
+		tx.history = append(tx.history, nextField)
+		tx.addPosting(nextField)
+		return tx.IsDone(), nil
 	}
 	tx.data[nextField.FieldIdentifierForValue()] = res
+	if nextField.FieldName == c.FIELD_AMOUNT {
+		if err := tx.validateAmounts(); err != nil {
+			delete(tx.data, nextField.FieldIdentifierForValue())
+			return tx.IsDone(), err
+		}
+		tx.selectedCurrency = ""
+	}
+	tx.history = append(tx.history, nextField)
 	return tx.IsDone(), nil
 }
 
+func (tx *SimpleTx) fieldHandler(f *TemplateField) func(m *tb.Message) (string, error) {
+	if f.FieldName == c.FIELD_AMOUNT {
+		isBalance := tx.template == TEMPLATE_BALANCE
+		return func(m *tb.Message) (string, error) {
+			var (
+				res string
+				err error
+			)
+			if isBalance {
+				res, err = handleBalanceAmount(m, tx.locale)
+			} else {
+				res, err = handleAmount(m, false, tx.locale)
+			}
+			if err != nil {
+				return "", err
+			}
+			res = withCurrency(withCurrency(res, tx.selectedCurrency), f.Currency)
+			if err := checkAllowedCurrencies(res, tx.allowedCommodities(f), tx.userCurrencySuggestion); err != nil {
+				return "", err
+			}
+			return res, nil
+		}
+	}
+	return TEMPLATE_TYPE_HINTS[Type(f.FieldName)].Handler
+}
+
+// Back reopens the field answered last, so that it is asked for again
+func (tx *SimpleTx) Back() error {
+	if len(tx.history) == 0 {
+		return fmt.Errorf("there is no previous step to go back to")
+	}
+	tx.selectedCurrency = ""
+	lastField := tx.history[len(tx.history)-1]
+	tx.history = tx.history[:len(tx.history)-1]
+	if _, isAnswered := tx.data[lastField.FieldIdentifierForValue()]; lastField.FieldName == c.FIELD_POSTING && !isAnswered {
+		// Undo adding a posting. The posting field is asked for again right away.
+		tx.removeLastPosting()
+		return nil
+	}
+	delete(tx.data, lastField.FieldIdentifierForValue())
+	tx.nextFields = append([]*TemplateField{lastField}, tx.nextFields...)
+	return nil
+}
+
 func (tx *SimpleTx) cleanNextFields() {
 	if len(tx.nextFields) > 0 {
 		nextField := tx.nextFields[0]
@@ -401,41 +753,69 @@ func (tx *SimpleTx) cleanNextFields() {
 	}
 }
 
+func (tx *SimpleTx) NextField() *TemplateField {
+	tx.cleanNextFields()
+	if len(tx.nextFields) == 0 {
+		return nil
+	}
+	return tx.nextFields[0]
+}
+
 func (tx *SimpleTx) NextHint(r *crud.Repo, m *tb.Message) *Hint {
 	if len(tx.nextFields) == 0 {
 		crud.LogDbf(r, TRACE, m, "During extraction of next hint an error ocurred: step exceeds max index.")
 		return nil
 	}
-	nextField := tx.nextFields[0]
-	hint := TEMPLATE_TYPE_HINTS[Type(nextField.FieldName)]
-	message, err := c.Template(hint.Text, structs.Map(nextField.TemplateHintData))
+	return tx.hintForField(r, m, tx.nextFields[0])
+}
+
+func (tx *SimpleTx) hintForField(r *crud.Repo, m *tb.Message, field *TemplateField) *Hint {
+	hint := TEMPLATE_TYPE_HINTS[Type(field.FieldName)]
+	message, err := c.Template(hint.Text, structs.Map(field.TemplateHintData))
 	if err != nil {
 		crud.LogDbf(r, TRACE, m, "During message building an error ocurred: "+err.Error())
 		return nil
 	}
-	return tx.EnrichHint(r, m, &Input{
-		key: nextField.FieldName,
+	enrichedHint := tx.EnrichHint(r, m, &Input{
+		key: field.FieldName,
 		hint: &Hint{
 			Prompt: message,
 		},
 		handler: hint.Handler,
-		field:   *nextField,
+		field:   *field,
 	})
+	if field.IsOptional && enrichedHint != nil {
+		enrichedHint.Prompt += fmt.Sprintf("\n\nThis field is optional. Select *%s* to leave it empty.", SKIP_OPTIONAL)
+		enrichedHint.KeyboardOptions = append([]string{SKIP_OPTIONAL}, enrichedHint.KeyboardOptions...)
+	}
+	return enrichedHint
 }
 
 func (tx *SimpleTx) EnrichHint(r *crud.Repo, m *tb.Message, i *Input) *Hint {
 	crud.LogDbf(r, TRACE, m, "Enriching hint (%s).", i.key)
-	if i.key == c.FIELD_DESCRIPTION {
+	if i.key == c.FIELD_DESCRIPTION || i.key == c.FIELD_PAYEE || i.key == c.FIELD_META {
 		return tx.hintDescription(r, m, i)
 	}
 	if i.key == c.FIELD_ACCOUNT {
 		return tx.hintAccount(r, m, i)
 	}
+	if i.key == c.FIELD_POSTING {
+		return tx.hintPosting(i)
+	}
+	if i.key == c.FIELD_AMOUNT {
+		return tx.hintAmount(r, m, i)
+	}
+	if i.key == c.FIELD_FLAG {
+		i.hint.KeyboardOptions = []string{FLAG_COMPLETED, FLAG_PENDING}
+	}
 	return i.hint
 }
 
 func (tx *SimpleTx) hintAccount(r *crud.Repo, m *tb.Message, i *Input) *Hint {
 	accountFQSpecifier := i.field.FieldIdentifierForValue()
+	if isAdditionalPosting(&i.field) {
+		accountFQSpecifier = c.FqCacheKey(c.FIELD_ACCOUNT + ":" + c.FIELD_ACCOUNT_TO)
+	}
 	crud.LogDbf(r, TRACE, m, "Enriching hint: '%s'", accountFQSpecifier)
 	var (
 		res []string = nil
@@ -456,10 +836,34 @@ func (tx *SimpleTx) hintDescription(r *crud.Repo, m *tb.Message, i *Input) *Hint
 	if err != nil {
 		crud.LogDbf(r, ERROR, m, "Error occurred getting cached hint (hintDescription): %s", err.Error())
 	}
+	if payee := tx.data[c.FqCacheKey(c.FIELD_PAYEE)]; i.key == c.FIELD_DESCRIPTION && payee != "" {
+		res = rankByPayee(r, m, res, payee)
+	}
 	i.hint.KeyboardOptions = res
 	return i.hint
 }
 
+// payeeDescriptionCacheKey is the cache key of the descriptions (narrations) used together with a payee before
+func payeeDescriptionCacheKey(payee string) string {
+	return c.FqCacheKey(c.FIELD_DESCRIPTION + ":@" + payee)
+}
+
+// rankByPayee moves the descriptions used together with the payee before to the front
+func rankByPayee(r *crud.Repo, m *tb.Message, descriptions []string, payee string) []string {
+	ranked, err := r.GetCacheHints(m, payeeDescriptionCacheKey(payee))
+	if err != nil {
+		crud.LogDbf(r, ERROR, m, "Error occurred getting cached descriptions for payee: %s", err.Error())
+		return descriptions
+	}
+	ranked = append([]string{}, ranked...)
+	for _, d := range descriptions {
+		if !c.ArrayContains(ranked, d) {
+			ranked = append(ranked, d)
+		}
+	}
+	return ranked
+}
+
 func (tx *SimpleTx) IsDone() bool {
 	tx.cleanNextFields()
 	return len(tx.nextFields) == 0
@@ -467,6 +871,9 @@ func (tx *SimpleTx) IsDone() bool {
 
 const FORMATTER_PLACEHOLDER = "${SPACE_FORMAT}"
 
+// Lines starting with a date are directives (e.g. 'balance') instead of postings and are not indented
+var directivePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\s`)
+
 func formatAllLinesWithFormatterPlaceholder(s string, dotIndentation int, currency string) string {
 	rebuiltString := ""
 	for _, line := range strings.Split(s, "\n") {
@@ -475,27 +882,33 @@ func formatAllLinesWithFormatterPlaceholder(s string, dotIndentation int, curren
 			firstPart, secondPart := strings.TrimSpace(splits[0]), strings.TrimSpace(splits[1])
 			// in case there are multiple occurrences in one line:
 			secondPart = strings.TrimSpace(strings.ReplaceAll(secondPart, FORMATTER_PLACEHOLDER, ""))
-			firstPart = fmt.Sprintf("  %s ", firstPart) // Two leading spaces and one trailing for separation
-			if !strings.Contains(secondPart, " ") {
+			if directivePattern.MatchString(firstPart) {
+				firstPart += " "
+			} else {
+				firstPart = fmt.Sprintf("  %s ", firstPart) // Two leading spaces and one trailing for separation
+			}
+			amountSplits := strings.SplitN(secondPart, " ", 2)
+			if len(amountSplits) == 1 {
 				secondPart += " " + currency
+			} else if isPriceAnnotation(amountSplits[1]) {
+				// Units without currency, but with price or cost annotation
+				secondPart = amountSplits[0] + " " + currency + " " + amountSplits[1]
 			}
-			if strings.Contains(secondPart, ".") {
-				dotSplits := strings.SplitN(secondPart, ".", 2)
-				runeCount := 0
-				runeCount += utf8.RuneCountInString(firstPart)
-				runeCount += utf8.RuneCountInString(dotSplits[0])
-				spacesNeeded := dotIndentation - runeCount + 1 // one dot char
-				if spacesNeeded < 0 {
-					spacesNeeded = 0
-				}
-				rebuiltString += firstPart + " " + strings.Repeat(" ", spacesNeeded) + secondPart + "\n"
-			} else {
-				rebuiltString += firstPart + " " + secondPart + "\n"
+			// Align on the decimal point. Amounts without decimal places are aligned as if it followed the last digit.
+			integerPart := strings.SplitN(amountSplits[0], ".", 2)[0]
+			runeCount := 0
+			runeCount += utf8.RuneCountInString(firstPart)
+			runeCount += utf8.RuneCountInString(integerPart)
+			spacesNeeded := dotIndentation - runeCount + 1 // one dot char
+			if spacesNeeded < 0 {
+				spacesNeeded = 0
 			}
+			rebuiltString += firstPart + " " + strings.Repeat(" ", spacesNeeded) + secondPart + "\n"
 		} else if line == "" {
 			rebuiltString += line
 		} else {
-			rebuiltString += line + "\n"
+			// Skipped optional fields might leave trailing spaces
+			rebuiltString += strings.TrimRight(line, " ") + "\n"
 		}
 	}
 	return rebuiltString
@@ -509,62 +922,161 @@ func (tx *SimpleTx) FillTemplate(currency, tag string, tzOffset int) (string, er
 	tx.setTimeIfEmpty(tzOffset)
 	tx.setTagIfEmpty(tag)
 
-	template := tx.template
+	template, err := tx.renderSplit(tx.template, currency)
+	if err != nil {
+		return "", err
+	}
+	template = tx.renderMetadata(template)
+	template = tx.renderConversions(template, currency)
 	fields := ParseTemplateFields(tx.template, "")
 	for _, f := range fields {
 		value, exists := tx.data[f.FieldIdentifierForValue()]
-		if exists {
-			value, err := applyFieldOptionsForNumbersIfApplicable(value, f)
+		if !exists {
+			continue
+		}
+		if f.FieldName == c.FIELD_PAYEE && value == "" {
+			// Transactions without payee only carry the narration
+			template = strings.ReplaceAll(template, fmt.Sprintf(`"${%s}" `, f.Raw), "")
+		}
+		placeholder := fmt.Sprintf("${%s}", f.Raw)
+		occurrences := strings.Count(template, placeholder)
+		if occurrences == 0 {
+			// Already filled for a previous field with the same raw representation
+			continue
+		}
+		filled, err := applyFieldOptionsForNumbersIfApplicable(value, f, currency, false)
+		if err != nil {
+			return "", err
+		}
+		if f.Fraction > 1 && occurrences == f.Fraction {
+			// Amount is split completely: Assign the rounding remainder to the last share, so that the transaction balances
+			remainder, err := applyFieldOptionsForNumbersIfApplicable(value, f, currency, true)
 			if err != nil {
 				return "", err
 			}
-			template = strings.ReplaceAll(template, fmt.Sprintf("${%s}", f.Raw), value)
+			template = strings.Replace(template, placeholder, filled, occurrences-1)
+			template = strings.Replace(template, placeholder, remainder, 1)
+		} else {
+			template = strings.ReplaceAll(template, placeholder, filled)
 		}
 	}
 	template = formatAllLinesWithFormatterPlaceholder(template, c.DOT_INDENT, currency)
 	return strings.TrimSpace(template) + "\n", nil
 }
 
-func applyFieldOptionsForNumbersIfApplicable(value string, f *TemplateField) (string, error) {
+var metadataFieldPattern = regexp.MustCompile(`\$\{(` + c.FIELD_META + `\??:[^}]*)\}`)
+
+// renderMetadata moves metadata fields (e.g. '${meta:receipt}') to their own lines 'receipt: "<value>"'.
+// These are placed below the transaction header or posting line the field appears in.
+// Fields on lines of their own belong to the header or posting above.
+func (tx *SimpleTx) renderMetadata(template string) string {
+	lines := []string{}
+	ownerIndent := ""
+	for _, line := range strings.Split(template, "\n") {
+		matches := metadataFieldPattern.FindAllStringSubmatch(line, -1)
+		if len(matches) == 0 {
+			lines = append(lines, line)
+			ownerIndent = metadataIndent(line)
+			continue
+		}
+		rest := strings.TrimRight(metadataFieldPattern.ReplaceAllString(line, ""), " ")
+		if strings.TrimSpace(rest) != "" {
+			lines = append(lines, rest)
+			ownerIndent = metadataIndent(rest)
+		}
+		for _, match := range matches {
+			field := ParseTemplateField(match[1], "")
+			value := tx.data[field.FieldIdentifierForValue()]
+			if field.FieldSpecifier == "" || value == "" {
+				// Skipped optional metadata is left out completely
+				continue
+			}
+			lines = append(lines, fmt.Sprintf(`%s%s: "%s"`, ownerIndent, field.FieldSpecifier, strings.ReplaceAll(value, `"`, `\"`)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// metadataIndent returns the indentation of metadata belonging to the line: Postings are indented, the transaction header is not.
+func metadataIndent(ownerLine string) string {
+	if strings.HasPrefix(ownerLine, " ") || strings.HasPrefix(ownerLine, "\t") {
+		return "    "
+	}
+	return "  "
+}
+
+// splitAmount splits a formatted amount value into its units, the currency (if specified) and a price annotation (if specified)
+func splitAmount(value string) (amount c.Decimal, currency string, annotation *PriceAnnotation, err error) {
+	amountSplits := strings.SplitN(strings.TrimSpace(value), " ", 2)
+	amount, err = c.ParseDecimal(amountSplits[0])
+	if err != nil {
+		return
+	}
+	if len(amountSplits) == 1 {
+		return
+	}
+	currency, annotation, err = splitPriceAnnotation(amountSplits[1], nil)
+	return
+}
+
+// splitShare returns the n-th part of an amount, rounded to the given precision.
+// The remainder share makes up for the rounding, so that all shares add up to the amount again.
+func splitShare(amount c.Decimal, n int, precision int, isRemainder bool) c.Decimal {
+	if places, _ := amount.Places(); places > precision {
+		precision = places
+	}
+	share := amount.Quo(c.NewDecimal(int64(n))).Round(precision)
+	if isRemainder {
+		return amount.Sub(share.Mul(c.NewDecimal(int64(n - 1))))
+	}
+	return share
+}
+
+func applyFieldOptionsForNumbersIfApplicable(value string, f *TemplateField, defaultCurrency string, isRemainder bool) (string, error) {
 	splits := strings.SplitN(value, FORMATTER_PLACEHOLDER, 2)
 	if len(splits) > 1 {
 		leftSide, rightSide := splits[0], splits[1]
-		if f.IsNegative {
-			if strings.HasPrefix(rightSide, "-") {
-				rightSide = rightSide[1:]
-			} else {
-				rightSide = "-" + rightSide
-			}
+		amount, currency, annotation, err := splitAmount(rightSide)
+		if err != nil {
+			return "", err
+		}
+		precisionCurrency := currency
+		if precisionCurrency == "" {
+			precisionCurrency = defaultCurrency
 		}
 		if f.Fraction > 1 {
-			amountSplits := strings.SplitN(rightSide, " ", 2)
-			amountLeft := amountSplits[0]
-			currency := ""
-			if len(amountSplits) > 1 {
-				currency = amountSplits[1]
+			amount = splitShare(amount, f.Fraction, c.CurrencyPrecision(precisionCurrency), isRemainder)
+			if annotation != nil && annotation.Operator == PRICE_TOTAL {
+				// A total price has to be split along with the units
+				annotation.Amount = splitShare(annotation.Amount, f.Fraction, c.CurrencyPrecision(annotation.Currency), isRemainder)
 			}
-			amountParsed, err := strconv.ParseFloat(amountLeft, 64)
+		}
+		if f.Expression != "" {
+			calculated, err := evaluateAmountExpression(f.Expression, amount, c.CurrencyPrecision(precisionCurrency))
 			if err != nil {
 				return "", err
 			}
-			amountParsed /= float64(f.Fraction)
-			rightSide = ParseAmount(amountParsed) + " " + currency
+			if annotation != nil && annotation.Operator == PRICE_TOTAL && !amount.IsZero() {
+				// A total price changes proportionally to the units
+				annotation.Amount = annotation.Amount.Mul(calculated.Abs()).Quo(amount.Abs()).Round(c.CurrencyPrecision(annotation.Currency))
+			}
+			amount = calculated
+		}
+		if f.IsNegative {
+			amount = amount.Neg()
+		}
+		rightSide = strings.TrimSpace(ParseAmount(amount, precisionCurrency) + " " + currency)
+		if annotation != nil {
+			rightSide += " " + annotation.String()
 		}
 		return leftSide + FORMATTER_PLACEHOLDER + rightSide, nil
 	}
 	return value, nil
 }
 
-func ParseAmount(f float64) string {
-	var amountF string
-	if math.Abs(math.Remainder(f*100, 1.0)) >= 1e-12 {
-		// float has more than 2 remainder digits (e.g. 17.234)
-		amountF = strings.TrimRight(fmt.Sprintf("%f", f), "0")
-	} else {
-		// at max 2 digits after the dot (e.g. 17.10)
-		amountF = fmt.Sprintf("%.2f", f)
-	}
-	return amountF
+// ParseAmount formats an amount with at least as many decimal places as the currency usually has
+func ParseAmount(d c.Decimal, currency string) string {
+	return d.Format(c.CurrencyPrecision(currency))
 }
 
 func (tx *SimpleTx) Debug() string {
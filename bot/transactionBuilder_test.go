@@ -7,7 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
 )
@@ -94,12 +96,20 @@ func TestHandleFloatSimpleCalculations(t *testing.T) {
 	helpers.TestExpect(t, err, nil, "Should not throw an error for 14.5+16+1+1+3 ANOTHER_CURRENCY")
 	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"35.50 ANOTHER_CURRENCY", "")
 
+	// Mixed calculation operators respect operator precedence
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "12.5+3*2"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for 12.5+3*2")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"18.50", "")
+
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "(45.90-5)/2 EUR"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for (45.90-5)/2 EUR")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"20.45 EUR", "")
+
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "1*1*1"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for 1*1*1")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"1.00", "")
+
 	// Check some error behaviors
-	// Mixed calculation operators
-	_, err = bot.HandleFloat(&tb.Message{Text: "1+1*2"})
-	if err == nil || !strings.Contains(err.Error(), "failed at value '1*2'") {
-		t.Errorf("Error message should state that mixing operators is not allowed: %e", err)
-	}
 	// Too many spaces in input
 	_, err = bot.HandleFloat(&tb.Message{Text: "some many spaces"})
 	if err == nil || !strings.Contains(err.Error(), "contained too many spaces") {
@@ -110,14 +120,9 @@ func TestHandleFloatSimpleCalculations(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "additionally specified currency is allowed") {
 		t.Errorf("Error message should state that no additionally specified currency is allowed for trailing + tx (left open)")
 	}
-	// Multiplications only work with exactly two multiplicators
-	_, err = bot.HandleFloat(&tb.Message{Text: "1*1*1"})
-	if err == nil || !strings.Contains(err.Error(), "exactly two multiplicators") {
-		t.Errorf("Error message should state that parser expected exactly two multiplicators")
-	}
 	// some hiccup value in multiplication
 	_, err = bot.HandleFloat(&tb.Message{Text: "1*EUR"})
-	if err == nil || !strings.Contains(err.Error(), "failed at value 'EUR'") {
+	if err == nil || !strings.Contains(err.Error(), "failed at value 'EUR' (position 3)") {
 		t.Errorf("Error message should state that it could not interpret 'EUR' as a number: %s", err.Error())
 	}
 }
@@ -168,6 +173,60 @@ func TestHandleFloatThousandsSeparator(t *testing.T) {
 	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"1024.00", "")
 }
 
+func TestHandleFloatPriceAnnotations(t *testing.T) {
+	handledFloat, err := bot.HandleFloat(&tb.Message{Text: "42.10 USD @ 0.92 EUR"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for per-unit price")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"42.10 USD @ 0.92 EUR", "")
+
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "42.10 USD @@ 38.73 EUR"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for total price")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"42.10 USD @@ 38.73 EUR", "")
+
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "10 VTI {210.55 USD}"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for cost")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"10.00 VTI {210.55 USD}", "")
+
+	handledFloat, err = bot.HandleFloat(&tb.Message{Text: "5+5 @ 1,5 EUR"})
+	helpers.TestExpect(t, err, nil, "Should not throw an error for calculation without unit currency")
+	helpers.TestExpect(t, handledFloat, bot.FORMATTER_PLACEHOLDER+"10.00 @ 1.50 EUR", "")
+
+	_, err = bot.HandleFloat(&tb.Message{Text: "42.10 USD @ EUR"})
+	if err == nil || !strings.Contains(err.Error(), "exactly one number and its currency") {
+		t.Errorf("Error message should state that price is missing: %v", err)
+	}
+	_, err = bot.HandleFloat(&tb.Message{Text: "10 VTI {210.55 USD"})
+	if err == nil || !strings.Contains(err.Error(), "missing its closing '}'") {
+		t.Errorf("Error message should state that cost is not closed: %v", err)
+	}
+	_, err = bot.HandleFloat(&tb.Message{Text: "42.10 USD @ -0.92 EUR"})
+	if err == nil || !strings.Contains(err.Error(), "must be positive") {
+		t.Errorf("Error message should state that price needs to be positive: %v", err)
+	}
+	_, err = bot.HandleFloat(&tb.Message{Text: "42.10+ @ 0.92 EUR"})
+	if err == nil || !strings.Contains(err.Error(), "additionally specified currency is allowed") {
+		t.Errorf("Error message should state that no annotation is allowed for trailing + tx (left open)")
+	}
+}
+
+func TestTransactionBuildingWithPriceAnnotation(t *testing.T) {
+	tx, _ := bot.CreateSimpleTx("", `${date} * "${description}"
+  Assets:Broker ${amount/2}
+  Assets:Broker ${amount/2}
+  Assets:Cash`)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "10 @@ 2110 USD"})
+	tx.Input(&tb.Message{Text: "Buy stocks"})
+	templated, err := tx.FillTemplate("VTI", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Buy stocks"
+  Assets:Broker                                 5.00 VTI @@ 1055.00 USD
+  Assets:Broker                                 5.00 VTI @@ 1055.00 USD
+  Assets:Cash
+`, "Templated string should contain aligned price annotations with split total price.")
+}
+
 func TestTransactionBuilding(t *testing.T) {
 	tx, err := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
 	if err != nil {
@@ -177,6 +236,7 @@ func TestTransactionBuilding(t *testing.T) {
 	tx.Input(&tb.Message{Text: "Buy something in the grocery store"}) // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})                      // from
 	tx.Input(&tb.Message{Text: "Expenses:Groceries"})                 // to
+	tx.Input(&tb.Message{Text: bot.POSTING_DONE})                     // no further postings
 
 	if !tx.IsDone() {
 		t.Errorf("With given input transaction data should be complete for SimpleTx")
@@ -193,6 +253,64 @@ func TestTransactionBuilding(t *testing.T) {
 `, "Templated string should be filled with variables as expected.")
 }
 
+func TestTransactionBuildingBack(t *testing.T) {
+	tx, _ := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
+	tx.SetDate("2022-04-11", 0)
+	if err := tx.Back(); err == nil {
+		t.Errorf("Going back without any given input should fail")
+	}
+	tx.Input(&tb.Message{Text: "17"})                  // amount
+	tx.Input(&tb.Message{Text: "Buy something"})       // description
+	tx.Input(&tb.Message{Text: "Assets:WrongAccount"}) // from
+	if err := tx.Back(); err != nil {
+		t.Errorf("Going back should work: %s", err.Error())
+	}
+	tx.Input(&tb.Message{Text: "Assets:Wallet"})      // from (again)
+	tx.Input(&tb.Message{Text: "Expenses:Groceries"}) // to
+	tx.Input(&tb.Message{Text: bot.POSTING_DONE})     // no further postings
+
+	templated, err := tx.FillTemplate("USD", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Buy something"
+  Assets:Wallet                               -17.00 USD
+  Expenses:Groceries
+`, "Templated string should contain the corrected account.")
+}
+
+func TestTransactionBuildingOptionalFields(t *testing.T) {
+	crud.TEST_MODE = true
+	tx, _ := bot.CreateSimpleTx("", `${date} * "${description?}"
+  Assets:Wallet ${-amount}
+  Expenses:Groceries ${amount?:fee}
+  Expenses:Other`)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "17"}) // amount
+
+	hint := tx.NextHint(nil, nil) // amount:fee
+	helpers.TestStringContains(t, hint.Prompt, "optional", "optional fields should be announced")
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{bot.SKIP_OPTIONAL}, "skipping should be offered")
+	tx.Input(&tb.Message{Text: bot.SKIP_OPTIONAL})
+	tx.Input(&tb.Message{Text: bot.SKIP_OPTIONAL}) // description
+
+	templated, err := tx.FillTemplate("USD", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * ""
+  Assets:Wallet                               -17.00 USD
+  Expenses:Groceries
+  Expenses:Other
+`, "Skipped fields should be left empty.")
+	helpers.TestExpect(t, len(tx.CacheData()), 0, "Skipped fields should not be cached")
+
+	_, err = bot.HandleFloat(&tb.Message{Text: bot.SKIP_OPTIONAL})
+	if err == nil {
+		t.Errorf("Non-optional fields can't be skipped")
+	}
+}
+
 func TestTransactionBuildingCustomCurrencyInAmount(t *testing.T) {
 	tx, err := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
 	if err != nil {
@@ -202,6 +320,7 @@ func TestTransactionBuildingCustomCurrencyInAmount(t *testing.T) {
 	tx.Input(&tb.Message{Text: "Buy something in the grocery store"}) // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})                      // from
 	tx.Input(&tb.Message{Text: "Expenses:Groceries"})                 // to
+	tx.Input(&tb.Message{Text: bot.POSTING_DONE})                     // no further postings
 
 	if !tx.IsDone() {
 		t.Errorf("With given input transaction data should be complete for SimpleTx")
@@ -218,9 +337,47 @@ func TestTransactionBuildingCustomCurrencyInAmount(t *testing.T) {
 `, "Templated string should be filled with variables as expected.")
 }
 
+func TestTransactionBuildingFractionsBalance(t *testing.T) {
+	tx, _ := bot.CreateSimpleTx("", `${date} * "Split"
+  Assets:Wallet ${-amount}
+  Expenses:A ${amount/3}
+  Expenses:B ${amount/3}
+  Expenses:C ${amount/3}
+  Expenses:Half ${amount/2}`)
+	tx.SetDate("2021-01-24", 0)
+	tx.Input(&tb.Message{Text: "10"})
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2021-01-24 * "Split"
+  Assets:Wallet                               -10.00 EUR
+  Expenses:A                                    3.33 EUR
+  Expenses:B                                    3.33 EUR
+  Expenses:C                                    3.34 EUR
+  Expenses:Half                                 5.00 EUR
+`, "Rounding remainder should be assigned to the last share")
+
+	tx, _ = bot.CreateSimpleTx("", `${date} * "Split"
+  Assets:Wallet ${-amount}
+  Expenses:A ${amount/3}
+  Expenses:B`)
+	tx.SetDate("2021-01-24", 0)
+	tx.Input(&tb.Message{Text: "1000"})
+	templated, err = tx.FillTemplate("JPY", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2021-01-24 * "Split"
+  Assets:Wallet                             -1000 JPY
+  Expenses:A                                  333 JPY
+  Expenses:B
+`, "Currency precision should be applied")
+}
+
 func TestTransactionBuildingWithDate(t *testing.T) {
 	tx, err := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
-	tx.SetDate("2021-01-24")
+	tx.SetDate("2021-01-24", 0)
 	if err != nil {
 		t.Errorf("Error creating simple tx: %s", err.Error())
 	}
@@ -228,6 +385,7 @@ func TestTransactionBuildingWithDate(t *testing.T) {
 	tx.Input(&tb.Message{Text: "Buy something in the grocery store"}) // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})                      // from
 	tx.Input(&tb.Message{Text: "Expenses:Groceries"})                 // to
+	tx.Input(&tb.Message{Text: bot.POSTING_DONE})                     // no further postings
 
 	if !tx.IsDone() {
 		t.Errorf("With given input transaction data should be complete for SimpleTx")
@@ -245,12 +403,13 @@ func TestTransactionBuildingWithDate(t *testing.T) {
 
 func TestTaggedTransaction(t *testing.T) {
 	tx, _ := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
-	tx.SetDate("2021-01-24")
+	tx.SetDate("2021-01-24", 0)
 	log.Print(tx.Debug())
 	tx.Input(&tb.Message{Text: "17.3456 USD_TEST"})   // amount
 	tx.Input(&tb.Message{Text: "Buy something"})      // description
 	tx.Input(&tb.Message{Text: "Assets:Wallet"})      // from
 	tx.Input(&tb.Message{Text: "Expenses:Groceries"}) // to
+	tx.Input(&tb.Message{Text: bot.POSTING_DONE})     // no further postings
 	template, err := tx.FillTemplate("EUR", "someTag", 0)
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
@@ -261,14 +420,26 @@ func TestTaggedTransaction(t *testing.T) {
 }
 
 func TestParseAmount(t *testing.T) {
-	helpers.TestExpect(t, bot.ParseAmount(-1), "-1.00", "At least two decimal places should be present")
-	helpers.TestExpect(t, bot.ParseAmount(0), "0.00", "At least two decimal places should be present")
-	helpers.TestExpect(t, bot.ParseAmount(17), "17.00", "At least two decimal places should be present")
-	helpers.TestExpect(t, bot.ParseAmount(16.8), "16.80", "At least two decimal places should be present")
-	helpers.TestExpect(t, bot.ParseAmount(9.8), "9.80", "At least two decimal places should be present")
-
-	helpers.TestExpect(t, bot.ParseAmount(9.801), "9.801", "If higher precision is given, that should be applied")
-	helpers.TestExpect(t, bot.ParseAmount(17.3456), "17.3456", "If higher precision is given, that should be applied")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "-1"), ""), "-1.00", "At least two decimal places should be present")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "0"), ""), "0.00", "At least two decimal places should be present")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "17"), ""), "17.00", "At least two decimal places should be present")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "16.8"), ""), "16.80", "At least two decimal places should be present")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "9.8"), ""), "9.80", "At least two decimal places should be present")
+
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "9.801"), ""), "9.801", "If higher precision is given, that should be applied")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "17.3456"), ""), "17.3456", "If higher precision is given, that should be applied")
+
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "1000"), "JPY"), "1000", "Currencies without minor unit should not get decimal places")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "0.5"), "BTC"), "0.50000000", "Currency precision should be applied")
+	helpers.TestExpect(t, bot.ParseAmount(decimal(t, "10").Quo(decimal(t, "3")), "EUR"), "3.33", "Non-terminating values should be rounded to currency precision")
+}
+
+func decimal(t *testing.T, s string) helpers.Decimal {
+	d, err := helpers.ParseDecimal(s)
+	if err != nil {
+		t.Errorf("Unexpected error parsing decimal: %s", err.Error())
+	}
+	return d
 }
 
 func TestParseTemplateFields(t *testing.T) {
@@ -282,33 +453,356 @@ func TestParseTemplateFields(t *testing.T) {
 	helpers.TestExpect(t, fields[1].FieldName, "description", "description field name")
 	helpers.TestExpect(t, fields[1].IsNegative, false, "description not be negative")
 	helpers.TestExpect(t, fields[1].Fraction, 1, "description fraction default = 1")
+	helpers.TestExpect(t, fields[1].IsOptional, false, "description not optional by default")
+
+	for raw, expression := range map[string]string{"(amount+2)*3": "(amount+2)*3", "-(amount)": "-(amount)", "-amount - 1.50": "-amount-1.50"} {
+		field := bot.ParseTemplateField(raw, "")
+		helpers.TestExpect(t, field.FieldName, "amount", "expression field name")
+		helpers.TestExpect(t, field.Expression, expression, "expression of the field")
+		helpers.TestExpect(t, field.IsNegative, false, "sign should be part of the expression")
+	}
+
+	field := bot.ParseTemplateField("description?:payee:the payee", "")
+	helpers.TestExpect(t, field.FieldName, "description", "optional field name")
+	helpers.TestExpect(t, field.FieldSpecifier, "payee", "")
+	helpers.TestExpect(t, field.IsOptional, true, "field should be optional")
 }
 
-func dateCase(t *testing.T, given, expected string) {
-	handledDate, err := bot.ParseDate(given)
+func dateCase(t *testing.T, today time.Time, given, expected string) {
+	handledDate, err := bot.ParseDateRelativeTo(given, today)
 	helpers.TestExpect(t, err, nil, fmt.Sprintf("Should not throw an error for %s", given))
-	helpers.TestExpect(t, handledDate, expected, "")
+	helpers.TestExpect(t, handledDate, expected, given)
 }
 
 func TestEnhancedDateParsing(t *testing.T) {
-	// helpers.BEANCOUNT_DATE_FORMAT
-	today := time.Now().UTC()
-	dateCase(t, "1999-04-14", "1999-04-14")
-	dateCase(t, "19990414", "1999-04-14")
-	dateCase(t, "03-31", fmt.Sprintf("%s-03-31", today.Format("2006")))
-	dateCase(t, "0331", fmt.Sprintf("%s-03-31", today.Format("2006")))
-	dateCase(t, "16", fmt.Sprintf("%s-16", today.Format("2006-01")))
-	dateCase(t, "16", fmt.Sprintf("%s-16", today.Format("2006-01")))
+	today := time.Date(2022, 4, 14, 0, 0, 0, 0, time.UTC) // Thursday
+	dateCase(t, today, "1999-04-14", "1999-04-14")
+	dateCase(t, today, "19990414", "1999-04-14")
+	dateCase(t, today, "03-31", "2022-03-31")
+	dateCase(t, today, "0331", "2022-03-31")
+	dateCase(t, today, "04-14", "2022-04-14")
+	dateCase(t, today, "10", "2022-04-10")
+	dateCase(t, today, "14", "2022-04-14")
+
+	// Partial dates must not be in the future
+	dateCase(t, today, "04-15", "2021-04-15")
+	dateCase(t, today, "1224", "2021-12-24")
+	dateCase(t, today, "16", "2022-03-16")
+	dateCase(t, today, "02-29", "2020-02-29")
+	dateCase(t, time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC), "31", "2022-03-31")
+
+	for _, invalid := range []string{"04-31", "32", "00", "-x", "last", "next friday"} {
+		if _, err := bot.ParseDateRelativeTo(invalid, today); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
 
-	if _, err := bot.ParseDate("04-31"); err == nil {
-		t.Errorf("Expected error for 04-31")
+func TestRelativeDateParsing(t *testing.T) {
+	today := time.Date(2022, 4, 14, 0, 0, 0, 0, time.UTC) // Thursday
+	dateCase(t, today, "today", "2022-04-14")
+	dateCase(t, today, "Yesterday", "2022-04-13")
+	dateCase(t, today, "-2", "2022-04-12")
+	dateCase(t, today, "-01", "2022-04-13")
+	dateCase(t, today, "-0", "2022-04-14")
+	dateCase(t, today, "-30", "2022-03-15")
+	dateCase(t, today, "mon", "2022-04-11")
+	dateCase(t, today, "thursday", "2022-04-14")
+	dateCase(t, today, "fri", "2022-04-08")
+	dateCase(t, today, "last friday", "2022-04-08")
+	dateCase(t, today, "last thu", "2022-04-07")
+	dateCase(t, today, "last  wed", "2022-04-13")
+}
+
+func TestDateParsingTimezone(t *testing.T) {
+	utcToday := time.Now().UTC()
+	date, _ := bot.ParseDate("today", 0)
+	helpers.TestExpect(t, date, utcToday.Format(helpers.BEANCOUNT_DATE_FORMAT), "")
+	date, _ = bot.ParseDate("today", 24)
+	helpers.TestExpect(t, date, utcToday.AddDate(0, 0, 1).Format(helpers.BEANCOUNT_DATE_FORMAT), "timezone offset should be honored")
+	date, _ = bot.ParseDate("yesterday", -24)
+	helpers.TestExpect(t, date, utcToday.AddDate(0, 0, -2).Format(helpers.BEANCOUNT_DATE_FORMAT), "")
+
+	tx, _ := bot.CreateSimpleTx("EUR", bot.TEMPLATE_SIMPLE_DEFAULT)
+	_, err := tx.SetDate("today", 24)
+	helpers.TestExpect(t, err, nil, "")
+	for _, input := range []string{"12", "Lunch", "Assets:Cash", "Expenses:Food", bot.POSTING_DONE} {
+		tx.Input(&tb.Message{Text: input})
 	}
+	templated, _ := tx.FillTemplate("EUR", "", 24)
+	helpers.TestStringContains(t, templated, utcToday.AddDate(0, 0, 1).Format(helpers.BEANCOUNT_DATE_FORMAT)+" * \"Lunch\"", "timezone offset should be honored when setting the date")
+}
 
-	if _, err := bot.ParseDate("32"); err == nil {
-		t.Errorf("Expected error for 32")
+func TestTransactionBuildingFlag(t *testing.T) {
+	crud.TEST_MODE = true
+	tx, _ := bot.CreateSimpleTx("", `${date} ${flag} "Groceries"
+  Assets:Wallet ${-amount}
+  Expenses:Groceries`)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "17"}) // amount
+
+	hint := tx.NextHint(nil, nil)
+	helpers.TestStringContains(t, hint.Prompt, "*flag*", "flag should be asked for")
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{bot.FLAG_COMPLETED, bot.FLAG_PENDING}, "")
+	_, err := tx.Input(&tb.Message{Text: "?"})
+	if err == nil {
+		t.Errorf("Unknown flags should be rejected")
+	}
+	tx.Input(&tb.Message{Text: "!"})
+
+	templated, err := tx.FillTemplate("USD", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
 	}
+	helpers.TestExpect(t, templated, `2022-04-11 ! "Groceries"
+  Assets:Wallet                               -17.00 USD
+  Expenses:Groceries
+`, "")
+
+	// The flag of simple transactions is not asked for
+	tx, _ = bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
+	tx.Input(&tb.Message{Text: "17"})
+	tx.Input(&tb.Message{Text: "Groceries"})
+	tx.Input(&tb.Message{Text: "Assets:Wallet"})
+	tx.Input(&tb.Message{Text: "Expenses:Groceries"})
+	isDone, _ := tx.Input(&tb.Message{Text: bot.POSTING_DONE})
+	helpers.TestExpect(t, isDone, true, "simple transaction should default to completed flag")
+}
+
+func TestTransactionBuildingPayee(t *testing.T) {
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	r := crud.NewRepo(db)
+	delete(crud.CACHE_LOCAL, chat.ID)
+
+	template := `${date} * "${payee?}" "${description}"
+  Assets:Wallet ${-amount}
+  Expenses:Groceries`
+	tx, _ := bot.CreateSimpleTx("", template)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "17"}) // amount
+
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("payee:", "Corner Shop").
+			AddRow("description:", "Lunch").
+			AddRow("description:", "Vegetables").
+			AddRow("description:", "Bread").
+			AddRow("description:@Corner Shop", "Bread").
+			AddRow("description:@Corner Shop", "Milk"))
+	hint := tx.NextHint(r, &tb.Message{Chat: chat})
+	helpers.TestStringContains(t, hint.Prompt, "*payee*", "payee should be asked for before the description")
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{bot.SKIP_OPTIONAL, "Corner Shop"}, "")
+	tx.Input(&tb.Message{Text: "Corner Shop"})
+
+	hint = tx.NextHint(r, &tb.Message{Chat: chat})
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{"Bread", "Milk", "Lunch", "Vegetables"}, "descriptions used with the payee should be ranked first")
+	tx.Input(&tb.Message{Text: "Vegetables"})
+
+	templated, _ := tx.FillTemplate("USD", "", 0)
+	helpers.TestStringContains(t, templated, `2022-04-11 * "Corner Shop" "Vegetables"`, "")
+	cacheData := tx.CacheData()
+	helpers.TestExpect(t, cacheData["payee:"], "Corner Shop", "payee should be cached")
+	helpers.TestExpect(t, cacheData["description:@Corner Shop"], "Vegetables", "description should be remembered for the payee")
+
+	// Skipped payee leaves the narration only
+	tx, _ = bot.CreateSimpleTx("", template)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"17", bot.SKIP_OPTIONAL, "Vegetables"} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	templated, _ = tx.FillTemplate("USD", "", 0)
+	helpers.TestStringContains(t, templated, `2022-04-11 * "Vegetables"`, "")
+	helpers.TestExpect(t, len(tx.CacheData()), 1, "only the description should be cached")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTransactionBuildingMetadata(t *testing.T) {
+	crud.TEST_MODE = true
+	template := `${date} * "${description}" ${meta:trip}
+  ${meta:location:where you have been}
+  Assets:Cash ${-amount} ${meta?:receipt}
+  Expenses:Food`
+	tx, _ := bot.CreateSimpleTx("", template)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "17"})
+	tx.Input(&tb.Message{Text: "Lunch"})
+	helpers.TestExpect(t, tx.NextField().FieldIdentifierForValue(), "meta:location", "")
+	tx.Input(&tb.Message{Text: `Piazza "Navona"`})
+	tx.Input(&tb.Message{Text: "R-123"})
+	isDone, _ := tx.Input(&tb.Message{Text: "Rome 2022"})
+	helpers.TestExpect(t, isDone, true, "")
+
+	templated, err := tx.FillTemplate("USD", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Lunch"
+  trip: "Rome 2022"
+  location: "Piazza \"Navona\""
+  Assets:Cash                                 -17.00 USD
+    receipt: "R-123"
+  Expenses:Food
+`, "metadata should be placed below header and posting")
+	helpers.TestExpect(t, tx.CacheData()["meta:receipt"], "R-123", "metadata should be cached per key")
+
+	// Skipped optional metadata is left out
+	tx, _ = bot.CreateSimpleTx("", template)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"17", "Lunch", "Colosseum", bot.SKIP_OPTIONAL, "Rome 2022"} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	templated, _ = tx.FillTemplate("USD", "", 0)
+	helpers.TestExpect(t, strings.Contains(templated, "receipt"), false, "skipped metadata should not be rendered")
+}
+
+func TestTransactionBuildingSplitPostings(t *testing.T) {
+	crud.TEST_MODE = true
+	chat := &tb.Chat{ID: 12345}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	r := crud.NewRepo(db)
+	delete(crud.CACHE_LOCAL, chat.ID)
+
+	tx, _ := bot.CreateSimpleTx("EUR", bot.TEMPLATE_SIMPLE_DEFAULT)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"30", "Dinner", "Assets:Wallet", "Expenses:Food"} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	hint := tx.NextHint(nil, nil)
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{bot.POSTING_DONE, bot.POSTING_ADD}, "")
+
+	tx.Input(&tb.Message{Text: bot.POSTING_ADD})
+	mock.ExpectQuery(`SELECT "type", "value" FROM "bot::cache"`).WithArgs(chat.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("account:from", "Assets:Wallet").
+			AddRow("account:to", "Expenses:Drinks"))
+	hint = tx.NextHint(r, &tb.Message{Chat: chat})
+	helpers.TestStringContains(t, hint.Prompt, "*additional posting*", "account of the new posting should be asked for")
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{"Expenses:Drinks"}, "accounts money went to should be suggested")
+	if err := tx.Back(); err != nil {
+		t.Errorf("Going back should work: %s", err.Error())
+	}
+	helpers.TestStringContains(t, tx.NextHint(nil, nil).Prompt, "*add another posting*", "going back should remove the added posting again")
+
+	tx.Input(&tb.Message{Text: bot.POSTING_ADD})
+	tx.Input(&tb.Message{Text: "Expenses:Drinks"})
+	tx.Input(&tb.Message{Text: "10"})
+	helpers.TestStringContains(t, tx.NextHint(nil, nil).Prompt, "remaining amount of 20.00 EUR", "")
+
+	tx.Input(&tb.Message{Text: bot.POSTING_ADD})
+	tx.Input(&tb.Message{Text: "Expenses:Tip"})
+	_, err = tx.Input(&tb.Message{Text: "25"})
+	if err == nil {
+		t.Errorf("Additional postings exceeding the amount should be rejected")
+	}
+	tx.Input(&tb.Message{Text: "5"})
+	isDone, _ := tx.Input(&tb.Message{Text: bot.POSTING_DONE})
+	helpers.TestExpect(t, isDone, true, "")
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Dinner"
+  Assets:Wallet                               -30.00 EUR
+  Expenses:Drinks                              10.00 EUR
+  Expenses:Tip                                  5.00 EUR
+  Expenses:Food
+`, "additional postings should be added before the last posting")
+
+	_, isCached := tx.CacheData()["account:posting1"]
+	helpers.TestExpect(t, isCached, false, "additional postings should not be cached")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTransactionBuildingLocale(t *testing.T) {
+	de, _ := helpers.GetLocale("de")
+	tx, _ := bot.CreateSimpleTx("", `${date} * "Rent"
+  Assets:Wallet ${-amount}
+  Expenses:Rent`)
+	tx.SetDate("2022-04-11", 0)
+	tx.SetLocale(de)
+	_, err := tx.Input(&tb.Message{Text: "1.5"})
+	if err == nil {
+		t.Errorf("'.' should only be accepted as grouping separator")
+	}
+	tx.Input(&tb.Message{Text: "1.234,5 USD @ 0,92 EUR"})
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Rent"
+  Assets:Wallet                             -1234.50 USD @ 0.92 EUR
+  Expenses:Rent
+`, "amounts should be recorded in canonical form")
+}
+
+func TestTransactionBuildingAmountExpressions(t *testing.T) {
+	tx, _ := bot.CreateSimpleTx("", `${date} * "Card payment"
+  Assets:Checking ${-amount}
+  Expenses:Fees ${amount*1.5%}
+  Expenses:Shopping ${amount - amount*1.5%}
+  Liabilities:VAT ${amount/1.19}`)
+	tx.SetDate("2022-04-11", 0)
+	helpers.TestExpect(t, tx.NextField().FieldIdentifierForValue(), "amount:", "expressions should refer to the entered amount")
+	isDone, _ := tx.Input(&tb.Message{Text: "33.33"})
+	helpers.TestExpect(t, isDone, true, "the amount should only be asked for once")
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-11 * "Card payment"
+  Assets:Checking                             -33.33 EUR
+  Expenses:Fees                                 0.50 EUR
+  Expenses:Shopping                            32.83 EUR
+  Liabilities:VAT                              28.01 EUR
+`, "calculated amounts should be rounded exactly to the currency precision")
+
+	tx, _ = bot.CreateSimpleTx("", `${date} * "Purchase"
+  Assets:Checking ${-amount}
+  Expenses:Shopping ${amount*0.5}`)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "40 USD @@ 36.80 EUR"})
+	templated, _ = tx.FillTemplate("EUR", "", 0)
+	helpers.TestStringContains(t, templated, "20.00 USD @@ 18.40 EUR", "total price should change along with the units")
+}
+
+func TestTransactionBuildingNegativeAmountExpression(t *testing.T) {
+	tx, _ := bot.CreateSimpleTx("", `${date} * "Transfer"
+  Assets:Checking ${-amount-1.50}
+  Expenses:Fees 1.50
+  Assets:Savings ${amount}`)
+	tx.SetDate("2022-04-11", 0)
+	tx.Input(&tb.Message{Text: "10"})
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestStringContains(t, templated, "Assets:Checking                             -11.50 EUR", "a leading '-' should be part of the expression")
+}
 
-	if _, err := bot.ParseDate("-01"); err == nil {
-		t.Errorf("Expected error for 32")
+func TestSortTemplateFields(t *testing.T) {
+	fields := bot.SortTemplateFields(bot.ParseTemplateFields(`${posting} ${meta:receipt} ${flag} ${account:to} ${account:from} ${amount}`, ""))
+	order := []string{}
+	for _, f := range fields {
+		order = append(order, f.FieldIdentifierForValue())
 	}
+	helpers.TestExpectArrEq(t, order, []string{"amount:", "account:from", "account:to", "meta:receipt", "flag:", "posting:"}, "other fields should keep their order, postings come last")
 }
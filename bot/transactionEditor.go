@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	c "github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+const EDIT_SAVE = "Save"
+
+// EditTx reopens an already recorded transaction. The user can change single fields
+// until selecting EDIT_SAVE, which updates the recorded transaction in place.
+type EditTx struct {
+	*SimpleTx
+	ElementId  int
+	IsArchived bool
+
+	tzOffset      int
+	selectedField *TemplateField
+	isDone        bool
+}
+
+func CreateEditTx(serialized, suggestedCur string, tzOffset int, elementId int, isArchived bool) (*EditTx, error) {
+	simpleTx, err := RestoreSimpleTx(serialized, suggestedCur)
+	if err != nil {
+		return nil, err
+	}
+	if !simpleTx.IsDone() {
+		return nil, fmt.Errorf("the recorded transaction data is incomplete")
+	}
+	return &EditTx{
+		SimpleTx:   simpleTx,
+		ElementId:  elementId,
+		IsArchived: isArchived,
+		tzOffset:   tzOffset,
+	}, nil
+}
+
+func fieldLabel(f *TemplateField) string {
+	if f.FieldSpecifier == "" {
+		return f.FieldName
+	}
+	return f.FieldName + ":" + f.FieldSpecifier
+}
+
+// editableFields returns all fields of the template the user has been asked for, plus the date
+func (tx *EditTx) editableFields() []*TemplateField {
+	fields := []*TemplateField{}
+	seen := map[string]bool{}
+	for _, f := range ParseTemplateFields(tx.template, tx.userCurrencySuggestion) {
+		if _, isAskedFor := TEMPLATE_TYPE_HINTS[Type(f.FieldName)]; !isAskedFor || f.FieldName == c.FIELD_POSTING || seen[f.FieldIdentifierForValue()] {
+			continue
+		}
+		seen[f.FieldIdentifierForValue()] = true
+		fields = append(fields, f)
+	}
+	return append(fields, ParseTemplateField(c.FIELD_DATE, ""))
+}
+
+func (tx *EditTx) Input(m *tb.Message) (isDone bool, err error) {
+	if tx.selectedField == nil {
+		selection := strings.TrimSpace(m.Text)
+		if selection == EDIT_SAVE {
+			// The same checks as when recording the transaction
+			if err := tx.validateAmounts(); err != nil {
+				return tx.IsDone(), err
+			}
+			tx.isDone = true
+			return tx.IsDone(), nil
+		}
+		for _, f := range tx.editableFields() {
+			if fieldLabel(f) == selection {
+				tx.selectedField = f
+				return tx.IsDone(), nil
+			}
+		}
+		return tx.IsDone(), fmt.Errorf("'%s' is not a field of this transaction. Please select a field from the list or '%s'", selection, EDIT_SAVE)
+	}
+	var res string
+	if tx.selectedField.IsOptional && strings.TrimSpace(m.Text) == SKIP_OPTIONAL {
+		res = ""
+	} else if tx.selectedField.FieldName == c.FIELD_DATE {
+		res, err = ParseDate(m.Text, tx.tzOffset)
+	} else {
+		if tx.selectedField.FieldName == c.FIELD_AMOUNT {
+			if isSelection, err := tx.selectCurrency(tx.selectedField, m); isSelection {
+				return tx.IsDone(), err
+			}
+		}
+		res, err = tx.fieldHandler(tx.selectedField)(m)
+	}
+	if err != nil {
+		return tx.IsDone(), err
+	}
+	key := tx.selectedField.FieldIdentifierForValue()
+	previous, hadValue := tx.data[key]
+	tx.data[key] = res
+	if tx.selectedField.FieldName == c.FIELD_AMOUNT {
+		if err := tx.validateAmounts(); err != nil {
+			if hadValue {
+				tx.data[key] = previous
+			} else {
+				delete(tx.data, key)
+			}
+			return tx.IsDone(), err
+		}
+	}
+	tx.selectedField = nil
+	tx.selectedCurrency = ""
+	return tx.IsDone(), nil
+}
+
+// Back returns from entering a new field value to the field selection
+func (tx *EditTx) Back() error {
+	if tx.selectedField == nil {
+		return fmt.Errorf("there is no previous step to go back to. Select '%s' to update the transaction or /cancel the editing", EDIT_SAVE)
+	}
+	tx.selectedField = nil
+	tx.selectedCurrency = ""
+	return nil
+}
+
+func (tx *EditTx) NextField() *TemplateField {
+	return tx.selectedField
+}
+
+func (tx *EditTx) IsDone() bool {
+	return tx.isDone
+}
+
+func (tx *EditTx) NextHint(r *crud.Repo, m *tb.Message) *Hint {
+	if tx.selectedField == nil {
+		options := []string{}
+		currentValues := ""
+		for _, f := range tx.editableFields() {
+			options = append(options, fieldLabel(f))
+			value := strings.ReplaceAll(tx.data[f.FieldIdentifierForValue()], FORMATTER_PLACEHOLDER, "")
+			if f.FieldName == c.FIELD_AMOUNT {
+				value = tx.locale.Format(value)
+			}
+			currentValues += fmt.Sprintf("\n%s: %s", escapeMarkdownValue(fieldLabel(f)), escapeMarkdownValue(value))
+		}
+		options = append(options, EDIT_SAVE)
+		return &Hint{
+			Prompt: "Please select the *field* you would like to change. Current values:\n" + currentValues +
+				fmt.Sprintf("\n\nSelect *%s* once you are done to update the recorded transaction.", EDIT_SAVE),
+			KeyboardOptions: options,
+		}
+	}
+	if tx.selectedField.FieldName == c.FIELD_DATE {
+		return &Hint{Prompt: "Please enter the new *date* of the transaction (e.g. " + escapeMarkdownValue("YYYY-MM-DD, MM-DD or DD") + ")"}
+	}
+	return tx.hintForField(r, m, tx.selectedField)
+}
+
+func (tx *EditTx) Debug() string {
+	return fmt.Sprintf("EditTx{elementId=%d, selectedField=%v, isDone=%t, data=%v}", tx.ElementId, tx.selectedField, tx.isDone, tx.data)
+}
+
+// escapeMarkdownValue escapes user data for MarkdownV2 prompts.
+// '(', ')', '.' and '!' are left out, as they are escaped for all prompts when sending hints.
+func escapeMarkdownValue(s string) string {
+	return escapeCharacters(s, "\\", "_", "*", "[", "]", "~", "`", ">", "#", "+", "-", "=", "|", "{", "}")
+}
@@ -0,0 +1,119 @@
+package bot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/bot"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func recordedSimpleTx(t *testing.T) string {
+	tx, _ := bot.CreateSimpleTx("", bot.TEMPLATE_SIMPLE_DEFAULT)
+	tx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"17.34", "Buy something", "Assets:Wallet", "Expenses:Groceries", bot.POSTING_DONE} {
+		tx.Input(&tb.Message{Text: input})
+	}
+	_, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	serialized, err := tx.Serialize()
+	if err != nil {
+		t.Errorf("There should be no error raised during serialization: %s", err.Error())
+	}
+	return serialized
+}
+
+func TestEditTxChangeFields(t *testing.T) {
+	tx, err := bot.CreateEditTx(recordedSimpleTx(t), "EUR", 0, 123, false)
+	if err != nil {
+		t.Fatalf("There should be no error restoring the transaction: %s", err.Error())
+	}
+	helpers.TestExpect(t, tx.IsDone(), false, "edit should wait for the user to save")
+
+	hint := tx.NextHint(nil, nil)
+	helpers.TestExpectArrEq(t, hint.KeyboardOptions, []string{"amount", "description", "account:from", "account:to", "flag", "date", bot.EDIT_SAVE}, "")
+	helpers.TestStringContains(t, hint.Prompt, "Buy something", "current values should be shown")
+
+	_, err = tx.Input(&tb.Message{Text: "payee"})
+	if err == nil {
+		t.Errorf("Selecting an unknown field should fail")
+	}
+
+	tx.Input(&tb.Message{Text: "amount"})
+	helpers.TestStringContains(t, tx.NextHint(nil, nil).Prompt, "*amount*", "amount should be asked for")
+	_, err = tx.Input(&tb.Message{Text: "abc"})
+	if err == nil {
+		t.Errorf("Invalid amounts should still be rejected")
+	}
+	tx.Input(&tb.Message{Text: "20.5*2"})
+
+	tx.Input(&tb.Message{Text: "description"})
+	if err := tx.Back(); err != nil {
+		t.Errorf("Going back to the field selection should work: %s", err.Error())
+	}
+	if err := tx.Back(); err == nil {
+		t.Errorf("Going back from the field selection should fail")
+	}
+
+	tx.Input(&tb.Message{Text: "date"})
+	helpers.TestStringContains(t, tx.NextHint(nil, nil).Prompt, "*date*", "date should be asked for")
+	tx.Input(&tb.Message{Text: "2022-04-12"})
+
+	isDone, _ := tx.Input(&tb.Message{Text: bot.EDIT_SAVE})
+	helpers.TestExpect(t, isDone, true, "saving should finish editing")
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestExpect(t, templated, `2022-04-12 * "Buy something"
+  Assets:Wallet                               -41.00 EUR
+  Expenses:Groceries
+`, "")
+}
+
+func TestEditTxInvalidData(t *testing.T) {
+	_, err := bot.CreateEditTx("not json", "EUR", 0, 123, false)
+	if err == nil {
+		t.Errorf("Restoring invalid data should fail")
+	}
+	incomplete := strings.Replace(recordedSimpleTx(t), `"account:to":"Expenses:Groceries"`, `"x":"y"`, 1)
+	_, err = bot.CreateEditTx(incomplete, "EUR", 0, 123, false)
+	if err == nil {
+		t.Errorf("Restoring incomplete data should fail")
+	}
+}
+
+func TestEditTxValidateAmounts(t *testing.T) {
+	simpleTx, _ := bot.CreateSimpleTx("EUR", bot.TEMPLATE_SIMPLE_DEFAULT)
+	simpleTx.SetDate("2022-04-11", 0)
+	for _, input := range []string{"30", "Dinner", "Assets:Wallet", "Expenses:Food", bot.POSTING_ADD, "Expenses:Drinks", "10", bot.POSTING_DONE} {
+		simpleTx.Input(&tb.Message{Text: input})
+	}
+	serialized, err := simpleTx.Serialize()
+	if err != nil {
+		t.Fatalf("There should be no error raised during serialization: %s", err.Error())
+	}
+	tx, err := bot.CreateEditTx(serialized, "EUR", 0, 123, false)
+	if err != nil {
+		t.Fatalf("There should be no error restoring the transaction: %s", err.Error())
+	}
+
+	tx.Input(&tb.Message{Text: "amount"})
+	_, err = tx.Input(&tb.Message{Text: "5"})
+	if err == nil {
+		t.Errorf("Amounts leaving nothing for the last posting should be rejected")
+	}
+	tx.Input(&tb.Message{Text: "25"})
+	isDone, _ := tx.Input(&tb.Message{Text: bot.EDIT_SAVE})
+	helpers.TestExpect(t, isDone, true, "valid amounts should be saved")
+
+	templated, err := tx.FillTemplate("EUR", "", 0)
+	if err != nil {
+		t.Errorf("There should be no error raised during templating: %s", err.Error())
+	}
+	helpers.TestStringContains(t, templated, "Assets:Wallet                               -25.00 EUR", "")
+}
@@ -1,6 +1,8 @@
 package bot
 
 import (
+	"io"
+
 	tb "gopkg.in/telebot.v3"
 )
 
@@ -10,6 +12,7 @@ type IBot interface {
 	Handle(endpoint interface{}, h tb.HandlerFunc, m ...tb.MiddlewareFunc)
 	Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error)
 	Respond(c *tb.Callback, resp ...*tb.CallbackResponse) error
+	File(file *tb.File) (io.ReadCloser, error)
 	// custom by me:
 	Me() *tb.User
 	SendSilent(bc *BotController, to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error)
@@ -35,6 +38,10 @@ func (b *Bot) Respond(c *tb.Callback, resp ...*tb.CallbackResponse) error {
 	return b.bot.Respond(c, resp...)
 }
 
+func (b *Bot) File(file *tb.File) (io.ReadCloser, error) {
+	return b.bot.File(file)
+}
+
 func (b *Bot) Me() *tb.User {
 	return b.bot.Me
 }
@@ -0,0 +1,79 @@
+package crud
+
+import (
+	"fmt"
+
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+// GetAccounts returns the currently open accounts of the user's account registry
+func (r *Repo) GetAccounts(m *tb.Message) ([]string, error) {
+	LogDbf(r, helpers.TRACE, m, "Getting open accounts")
+	rows, err := r.db.Query(`
+		SELECT "account" FROM "bot::account"
+		WHERE "tgChatId" = $1 AND "closed" IS NULL
+		ORDER BY "account" ASC
+	`, m.Chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []string{}
+	var account string
+	for rows.Next() {
+		err = rows.Scan(&account)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// AddAccount opens an account in the user's account registry. Closed accounts are reopened.
+func (r *Repo) AddAccount(m *tb.Message, account string) error {
+	LogDbf(r, helpers.TRACE, m, "Adding account '%s'", account)
+	_, err := r.db.Exec(`
+		INSERT INTO "bot::account" ("tgChatId", "account")
+		VALUES ($1, $2)
+		ON CONFLICT ("tgChatId", "account") DO UPDATE SET "opened" = NOW(), "closed" = NULL`, m.Chat.ID, account)
+	return err
+}
+
+// SeedAccount opens an account in the user's account registry, if it has not been added before (also not as closed account)
+func (r *Repo) SeedAccount(m *tb.Message, account string) (added bool, err error) {
+	res, err := r.db.Exec(`
+		INSERT INTO "bot::account" ("tgChatId", "account")
+		VALUES ($1, $2)
+		ON CONFLICT ("tgChatId", "account") DO NOTHING`, m.Chat.ID, account)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	return count > 0, err
+}
+
+func (r *Repo) CloseAccount(m *tb.Message, account string) error {
+	LogDbf(r, helpers.TRACE, m, "Closing account '%s'", account)
+	res, err := r.db.Exec(`
+		UPDATE "bot::account"
+		SET "closed" = NOW()
+		WHERE "tgChatId" = $1 AND "account" = $2 AND "closed" IS NULL`, m.Chat.ID, account)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return fmt.Errorf("the account '%s' is not open in your registry", account)
+	}
+	return nil
+}
+
+func (r *Repo) DeleteAccounts(m *tb.Message) error {
+	LogDbf(r, helpers.TRACE, m, "Permanently deleting accounts")
+	_, err := r.db.Exec(`
+		DELETE FROM "bot::account"
+		WHERE "tgChatId" = $1`, m.Chat.ID)
+	return err
+}
@@ -0,0 +1,77 @@
+package crud_test
+
+import (
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LucaBernstein/beancount-bot-tg/db/crud"
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestAccounts(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	r := crud.NewRepo(db)
+	m := &tb.Message{Chat: &tb.Chat{ID: 1122}}
+
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(1122, "Assets:Cash").WillReturnResult(sqlmock.NewResult(1, 1))
+	err = r.AddAccount(m, "Assets:Cash")
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+
+	mock.ExpectQuery(`SELECT "account" FROM "bot::account"`).WithArgs(1122).
+		WillReturnRows(sqlmock.NewRows([]string{"account"}).AddRow("Assets:Cash").AddRow("Expenses:Food"))
+	accounts, err := r.GetAccounts(m)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+	helpers.TestExpectArrEq(t, accounts, []string{"Assets:Cash", "Expenses:Food"}, "")
+
+	mock.ExpectExec(`UPDATE "bot::account"`).WithArgs(1122, "Assets:Cash").WillReturnResult(sqlmock.NewResult(0, 1))
+	err = r.CloseAccount(m, "Assets:Cash")
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+
+	mock.ExpectExec(`UPDATE "bot::account"`).WithArgs(1122, "Assets:Cash").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.CloseAccount(m, "Assets:Cash")
+	if err == nil {
+		t.Errorf("Closing an account which is not open should fail")
+	}
+
+	mock.ExpectExec(`INSERT INTO "bot::account"`).WithArgs(1122, "Assets:Cash").WillReturnResult(sqlmock.NewResult(0, 0))
+	added, err := r.SeedAccount(m, "Assets:Cash")
+	if err != nil || added {
+		t.Errorf("Existing accounts should not be seeded again: %t %v", added, err)
+	}
+
+	mock.ExpectQuery(`SELECT "type", "value"`).WithArgs(1122).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "value"}).
+			AddRow("account:from", "Assets:Cash").
+			AddRow("account:to", "Expenses:Food").
+			AddRow("account:to", "Assets:Cash").
+			AddRow("description:", "Coffee"))
+	accounts, err = r.GetCachedAccounts(m)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+	helpers.TestExpectArrEq(t, accounts, []string{"Assets:Cash", "Expenses:Food"}, "")
+
+	mock.ExpectExec(`DELETE FROM "bot::account"`).WithArgs(1122).WillReturnResult(sqlmock.NewResult(0, 2))
+	err = r.DeleteAccounts(m)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -3,6 +3,7 @@ package crud
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
@@ -55,6 +56,27 @@ func (r *Repo) GetCacheHints(m *tb.Message, key string) ([]string, error) {
 	return cacheData, nil
 }
 
+// GetCachedAccounts returns all distinct values cached for any account type (e.g. 'account:from')
+func (r *Repo) GetCachedAccounts(m *tb.Message) ([]string, error) {
+	err := r.FillCache(m)
+	if err != nil {
+		return nil, err
+	}
+	accounts := []string{}
+	for key, values := range CACHE_LOCAL[m.Chat.ID] {
+		if helpers.TypeCacheKey(key) != helpers.FIELD_ACCOUNT {
+			continue
+		}
+		for _, value := range values {
+			if !helpers.ArrayContains(accounts, value) {
+				accounts = append(accounts, value)
+			}
+		}
+	}
+	sort.Strings(accounts)
+	return accounts, nil
+}
+
 func (r *Repo) FillCache(m *tb.Message) error {
 	r.DeleteCache(m)
 	rows, err := r.db.Query(`
@@ -2,6 +2,7 @@ package crud
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
@@ -12,7 +13,25 @@ type TemplateResult struct {
 	Template string
 }
 
-const DB_TABLE_TEMPLATES = "bot::template"
+// TemplateVersion is a previous version of a template, kept when the template is edited
+type TemplateVersion struct {
+	Id       int
+	Template string
+	Replaced time.Time
+}
+
+// TemplateUsage is the category of a template and how often it has been used
+type TemplateUsage struct {
+	Name       string
+	Category   string
+	UsageCount int
+}
+
+const (
+	DB_TABLE_TEMPLATES        = "bot::template"
+	DB_TABLE_TEMPLATE_HISTORY = "bot::templateHistory"
+	DB_TABLE_TEMPLATE_SHARES  = "bot::templateShare"
+)
 
 func (r *Repo) GetTemplates(m *tb.Message, name string) ([]*TemplateResult, error) {
 	LogDbf(r, helpers.TRACE, m, "Getting template(s), '%s'", name)
@@ -53,6 +72,51 @@ func (r *Repo) GetTemplates(m *tb.Message, name string) ([]*TemplateResult, erro
 	return results, nil
 }
 
+// GetTemplateUsage returns the categories of all templates, most frequently used first
+func (r *Repo) GetTemplateUsage(m *tb.Message) ([]*TemplateUsage, error) {
+	LogDbf(r, helpers.TRACE, m, "Getting template usage")
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT "name", "category", "usageCount" FROM "%s"
+		WHERE "tgChatId" = $1
+		ORDER BY "usageCount" DESC, "name" ASC
+	`, DB_TABLE_TEMPLATES), m.Chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []*TemplateUsage{}
+	for rows.Next() {
+		usage := &TemplateUsage{}
+		err = rows.Scan(&usage.Name, &usage.Category, &usage.UsageCount)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// SetTemplateCategory sets the category the template is grouped by. An empty category removes it.
+func (r *Repo) SetTemplateCategory(chatId int64, name, category string) error {
+	res, err := r.db.Exec(fmt.Sprintf(`UPDATE "%s" SET "category" = $3 WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATES),
+		chatId, name, category)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("there is no template called '%s'", name)
+	}
+	return nil
+}
+
+// RecordTemplateUsage counts a use of the template, so that frequently used templates are offered first
+func (r *Repo) RecordTemplateUsage(chatId int64, name string) error {
+	_, err := r.db.Exec(fmt.Sprintf(`UPDATE "%s" SET "usageCount" = "usageCount" + 1 WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATES),
+		chatId, name)
+	return err
+}
+
 func (r *Repo) AddTemplate(chatId int64, name, template string) error {
 	_, err := r.db.Exec(fmt.Sprintf(`
 		INSERT INTO "%s" ("tgChatId", "name", "template")
@@ -61,8 +125,106 @@ func (r *Repo) AddTemplate(chatId int64, name, template string) error {
 }
 
 func (r *Repo) RmTemplate(chatId int64, name string) (bool, error) {
-	res, err := r.db.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATES),
+	res, err := r.db.Exec(fmt.Sprintf(`
+		WITH "history" AS (DELETE FROM "%s" WHERE "tgChatId" = $1 AND "name" = $2)
+		DELETE FROM "%s" WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATE_HISTORY, DB_TABLE_TEMPLATES),
 		chatId, name)
+	if err != nil {
+		return false, err
+	}
 	rows, _ := res.RowsAffected()
 	return rows > 0, err
 }
+
+// UpdateTemplate replaces the template. The previous version is kept in the template history.
+func (r *Repo) UpdateTemplate(chatId int64, name, template string) error {
+	res, err := r.db.Exec(fmt.Sprintf(`
+		WITH "previous" AS (
+			INSERT INTO "%s" ("tgChatId", "name", "template")
+			SELECT "tgChatId", "name", "template" FROM "%s"
+			WHERE "tgChatId" = $1 AND "name" = $2 AND "template" <> $3
+		)
+		UPDATE "%s" SET "template" = $3
+		WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATE_HISTORY, DB_TABLE_TEMPLATES, DB_TABLE_TEMPLATES),
+		chatId, name, template)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("there is no template called '%s'", name)
+	}
+	return nil
+}
+
+// RenameTemplate renames the template along with its history
+func (r *Repo) RenameTemplate(chatId int64, name, newName string) error {
+	res, err := r.db.Exec(fmt.Sprintf(`
+		WITH "history" AS (UPDATE "%s" SET "name" = $3 WHERE "tgChatId" = $1 AND "name" = $2)
+		UPDATE "%s" SET "name" = $3
+		WHERE "tgChatId" = $1 AND "name" = $2;`, DB_TABLE_TEMPLATE_HISTORY, DB_TABLE_TEMPLATES),
+		chatId, name, newName)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("there is no template called '%s'", name)
+	}
+	return nil
+}
+
+// GetTemplateHistory returns the previous versions of the template, most recently replaced first
+func (r *Repo) GetTemplateHistory(m *tb.Message, name string) ([]*TemplateVersion, error) {
+	LogDbf(r, helpers.TRACE, m, "Getting template history, '%s'", name)
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT "id", "template", "replaced" FROM "%s"
+		WHERE "tgChatId" = $1 AND "name" = $2
+		ORDER BY "id" DESC
+	`, DB_TABLE_TEMPLATE_HISTORY), m.Chat.ID, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*TemplateVersion{}
+	for rows.Next() {
+		version := &TemplateVersion{}
+		err = rows.Scan(&version.Id, &version.Template, &version.Replaced)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ShareTemplate stores a copy of the template under the code, so that other chats can import it.
+// Later changes to the template are not shared.
+func (r *Repo) ShareTemplate(chatId int64, code, name, template string) error {
+	_, err := r.db.Exec(fmt.Sprintf(`
+		INSERT INTO "%s" ("code", "tgChatId", "name", "template")
+		VALUES ($1, $2, $3, $4);`, DB_TABLE_TEMPLATE_SHARES), code, chatId, name, template)
+	return err
+}
+
+// GetSharedTemplate returns the template shared under the code, or nil if there is none
+func (r *Repo) GetSharedTemplate(m *tb.Message, code string) (*TemplateResult, error) {
+	LogDbf(r, helpers.TRACE, m, "Getting shared template '%s'", code)
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT "name", "template" FROM "%s"
+		WHERE "code" = $1
+	`, DB_TABLE_TEMPLATE_SHARES), code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	shared := &TemplateResult{}
+	err = rows.Scan(&shared.Name, &shared.Template)
+	if err != nil {
+		return nil, err
+	}
+	return shared, nil
+}
@@ -51,3 +51,36 @@ func TestGetTemplates(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestUpdateTemplate(t *testing.T) {
+	// create test dependencies
+	TEST_MODE = true
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	r := NewRepo(db)
+
+	mock.ExpectExec(`INSERT INTO "bot::templateHistory"`).
+		WithArgs(123, "rent", "new template").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = r.UpdateTemplate(123, "rent", "new template")
+	if err != nil {
+		t.Errorf("Should not fail for updating template: %s", err.Error())
+	}
+
+	mock.ExpectExec(`INSERT INTO "bot::templateHistory"`).
+		WithArgs(123, "unknown", "new template").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.UpdateTemplate(123, "unknown", "new template")
+	if err == nil {
+		t.Errorf("Updating a template that does not exist should fail")
+	}
+	helpers.TestExpect(t, err.Error(), "there is no template called 'unknown'", "error message")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,7 +1,9 @@
 package crud
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
@@ -17,6 +19,57 @@ func (r *Repo) RecordTransaction(chatId int64, tx string) error {
 	return err
 }
 
+// RecordTransactionWithData records a transaction along with the field data it has been built from,
+// so that it can be edited later on
+func (r *Repo) RecordTransactionWithData(chatId int64, tx, txData string) error {
+	if tx == "" {
+		return fmt.Errorf("a transaction inserted into the database must not be empty")
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO "bot::transaction" ("tgChatId", "value", "txData")
+		VALUES ($1, $2, $3);`, chatId, tx, txData)
+	return err
+}
+
+func (r *Repo) GetTransactionData(m *tb.Message, isArchived bool, elementId int) (string, error) {
+	LogDbf(r, helpers.TRACE, m, "Getting transaction data")
+	rows, err := r.db.Query(`
+		SELECT "txData" FROM "bot::transaction"
+		WHERE "tgChatId" = $1 AND "archived" = $2 AND "id" = $3
+	`, m.Chat.ID, isArchived, elementId)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var txData sql.NullString
+	if rows.Next() {
+		err = rows.Scan(&txData)
+		if err != nil {
+			return "", err
+		}
+	}
+	return txData.String, nil
+}
+
+func (r *Repo) UpdateTransaction(m *tb.Message, isArchived bool, elementId int, tx, txData string) error {
+	LogDbf(r, helpers.TRACE, m, "Updating single transaction")
+	if tx == "" {
+		return fmt.Errorf("a transaction updated in the database must not be empty")
+	}
+	res, err := r.db.Exec(`
+		UPDATE "bot::transaction"
+		SET "value" = $4, "txData" = $5
+		WHERE "tgChatId" = $1 AND "archived" = $2 AND "id" = $3`, m.Chat.ID, isArchived, elementId, tx, txData)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return fmt.Errorf("the transaction to be updated does not exist anymore")
+	}
+	return nil
+}
+
 type TransactionResult struct {
 	Id   int
 	Tx   string
@@ -62,6 +115,29 @@ func (r *Repo) ArchiveTransactions(m *tb.Message) error {
 	return err
 }
 
+// ArchiveTransactionsById archives exactly the given transactions, e.g. the ones exported before.
+// Transactions recorded in the meantime are left untouched.
+func (r *Repo) ArchiveTransactionsById(m *tb.Message, ids []int) (int64, error) {
+	LogDbf(r, helpers.TRACE, m, "Archiving %d transactions", len(ids))
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders := []string{}
+	params := []interface{}{m.Chat.ID}
+	for _, id := range ids {
+		params = append(params, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(params)))
+	}
+	res, err := r.db.Exec(fmt.Sprintf(`
+		UPDATE "bot::transaction"
+		SET "archived" = TRUE
+		WHERE "tgChatId" = $1 AND "archived" = FALSE AND "id" IN (%s)`, strings.Join(placeholders, ", ")), params...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (r *Repo) DeleteTransactions(m *tb.Message) error {
 	LogDbf(r, helpers.TRACE, m, "Permanently deleting transactions")
 	_, err := r.db.Exec(`
@@ -73,6 +149,8 @@ func (r *Repo) DeleteTransactions(m *tb.Message) error {
 func (r *Repo) DeleteTemplates(m *tb.Message) error {
 	LogDbf(r, helpers.TRACE, m, "Permanently deleting templates")
 	_, err := r.db.Exec(`
+		WITH "history" AS (DELETE FROM "bot::templateHistory" WHERE "tgChatId" = $1),
+			"shares" AS (DELETE FROM "bot::templateShare" WHERE "tgChatId" = $1)
 		DELETE FROM "bot::template"
 		WHERE "tgChatId" = $1`, m.Chat.ID)
 	return err
@@ -50,6 +50,58 @@ func TestRecordGetTransaction(t *testing.T) {
 	}
 }
 
+func TestRecordUpdateTransactionData(t *testing.T) {
+	// create test dependencies
+	crud.TEST_MODE = true
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	r := crud.NewRepo(db)
+	m := &tb.Message{Chat: &tb.Chat{ID: 1122}}
+
+	mock.ExpectExec(`INSERT INTO "bot::transaction" \("tgChatId", "value", "txData"\)`).WithArgs(1122, "txContent", `{"data":{}}`).WillReturnResult(sqlmock.NewResult(1, 1))
+	err = r.RecordTransactionWithData(1122, "txContent", `{"data":{}}`)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+
+	mock.ExpectQuery(`SELECT "txData" FROM "bot::transaction"`).WithArgs(1122, false, 123).
+		WillReturnRows(sqlmock.NewRows([]string{"txData"}).AddRow(`{"data":{}}`))
+	txData, err := r.GetTransactionData(m, false, 123)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+	if txData != `{"data":{}}` {
+		t.Errorf("Transaction data should have been returned: %s", txData)
+	}
+
+	// Transactions recorded without data (e.g. comments)
+	mock.ExpectQuery(`SELECT "txData" FROM "bot::transaction"`).WithArgs(1122, false, 124).
+		WillReturnRows(sqlmock.NewRows([]string{"txData"}).AddRow(nil))
+	txData, err = r.GetTransactionData(m, false, 124)
+	if err != nil || txData != "" {
+		t.Errorf("Transaction data should be empty without error: '%s' %v", txData, err)
+	}
+
+	mock.ExpectExec(`UPDATE "bot::transaction"`).WithArgs(1122, false, 123, "txNew", `{"data":{"a":"b"}}`).WillReturnResult(sqlmock.NewResult(0, 1))
+	err = r.UpdateTransaction(m, false, 123, "txNew", `{"data":{"a":"b"}}`)
+	if err != nil {
+		t.Errorf("No error should have been returned: %s", err.Error())
+	}
+
+	mock.ExpectExec(`UPDATE "bot::transaction"`).WithArgs(1122, false, 125, "txNew", "").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.UpdateTransaction(m, false, 125, "txNew", "")
+	if err == nil {
+		t.Errorf("Updating a non-existing transaction should fail")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestArchiveDeleteTransactions(t *testing.T) {
 	// create test dependencies
 	crud.TEST_MODE = true
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
 	tb "gopkg.in/telebot.v3"
@@ -116,6 +117,47 @@ func (r *Repo) UserSetTzOffset(m *tb.Message, timezoneOffset int) error {
 	return r.SetUserSetting(helpers.USERSET_TZOFF, tzOffsetS, m.Chat.ID)
 }
 
+// Locale
+
+// UserGetLocale returns the locale to parse and show amounts in, or nil to guess the separators from the input
+func (r *Repo) UserGetLocale(m *tb.Message) *helpers.Locale {
+	_, value, err := r.GetUserSetting(helpers.USERSET_LOCALE, m.Chat.ID)
+	if err != nil {
+		LogDbf(r, helpers.ERROR, m, "Could not get locale: %s", err.Error())
+		return nil
+	}
+	locale, err := helpers.GetLocale(value)
+	if err != nil {
+		LogDbf(r, helpers.ERROR, m, "Could not parse locale: %s", err.Error())
+		return nil
+	}
+	return locale
+}
+
+func (r *Repo) UserSetLocale(m *tb.Message, locale *helpers.Locale) error {
+	value := ""
+	if locale != nil {
+		value = locale.Name
+	}
+	return r.SetUserSetting(helpers.USERSET_LOCALE, value, m.Chat.ID)
+}
+
+// Commodities
+
+// UserGetCommodities returns the currencies accepted for amounts besides the user's default currency. If empty, any valid commodity is accepted.
+func (r *Repo) UserGetCommodities(m *tb.Message) []string {
+	_, value, err := r.GetUserSetting(helpers.USERSET_COMMODITIES, m.Chat.ID)
+	if err != nil {
+		LogDbf(r, helpers.ERROR, m, "Could not get commodities: %s", err.Error())
+		return nil
+	}
+	return strings.Fields(value)
+}
+
+func (r *Repo) UserSetCommodities(m *tb.Message, commodities []string) error {
+	return r.SetUserSetting(helpers.USERSET_COMMODITIES, strings.Join(commodities, " "), m.Chat.ID)
+}
+
 // Admin
 
 func (r *Repo) UserIsAdmin(m *tb.Message) (isAdmin bool) {
@@ -23,6 +23,13 @@ func Migrate(db *sql.DB) {
 	migrationWrapper(v10, 10)(db)
 	migrationWrapper(v11, 11)(db)
 	migrationWrapper(v12, 12)(db)
+	migrationWrapper(v13, 13)(db)
+	migrationWrapper(v14, 14)(db)
+	migrationWrapper(v15, 15)(db)
+	migrationWrapper(v16, 16)(db)
+	migrationWrapper(v17, 17)(db)
+	migrationWrapper(v18, 18)(db)
+	migrationWrapper(v19, 19)(db)
 
 	helpers.LogLocalf(helpers.INFO, nil, "Migrations ran through. Schema version: %d", schema(db))
 }
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v13(db *sql.Tx) {
+	v13AddTransactionData(db)
+}
+
+func v13AddTransactionData(db *sql.Tx) {
+	sqlStatement := `
+	ALTER TABLE "bot::transaction"
+	ADD "txData" TEXT DEFAULT NULL;
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
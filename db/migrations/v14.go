@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v14(db *sql.Tx) {
+	v14CreateAccountsTable(db)
+}
+
+func v14CreateAccountsTable(db *sql.Tx) {
+	sqlStatement := `
+	CREATE TABLE "bot::account" (
+		"id" SERIAL PRIMARY KEY,
+		"tgChatId" NUMERIC REFERENCES "auth::user" ("tgChatId") NOT NULL,
+		"account" TEXT NOT NULL,
+		"opened" TIMESTAMP NOT NULL DEFAULT NOW(),
+		"closed" TIMESTAMP DEFAULT NULL,
+		UNIQUE ("tgChatId", "account")
+	);
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v15(db *sql.Tx) {
+	v15AddLocaleSetting(db)
+}
+
+func v15AddLocaleSetting(db *sql.Tx) {
+	sqlStatement := `
+	INSERT INTO "bot::userSettingTypes" ("setting", "description") VALUES
+		('user.locale', 'decimal and grouping separators of amounts');
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v16(db *sql.Tx) {
+	v16AddCommoditiesSetting(db)
+}
+
+func v16AddCommoditiesSetting(db *sql.Tx) {
+	sqlStatement := `
+	INSERT INTO "bot::userSettingTypes" ("setting", "description") VALUES
+		('user.commodities', 'currencies accepted for amounts besides the default currency');
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v17(db *sql.Tx) {
+	v17CreateTemplateHistoryTable(db)
+}
+
+func v17CreateTemplateHistoryTable(db *sql.Tx) {
+	sqlStatement := `
+	CREATE TABLE "bot::templateHistory" (
+		"id" SERIAL PRIMARY KEY,
+		"tgChatId" NUMERIC REFERENCES "auth::user" ("tgChatId") NOT NULL,
+		"name" TEXT NOT NULL,
+		"template" TEXT NOT NULL,
+		"replaced" TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v18(db *sql.Tx) {
+	v18AddTemplateCategoryAndUsage(db)
+}
+
+func v18AddTemplateCategoryAndUsage(db *sql.Tx) {
+	sqlStatement := `
+	ALTER TABLE "bot::template"
+		ADD COLUMN "category" TEXT NOT NULL DEFAULT '',
+		ADD COLUMN "usageCount" INTEGER NOT NULL DEFAULT 0;
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"database/sql"
+	"log"
+)
+
+func v19(db *sql.Tx) {
+	v19CreateTemplateShareTable(db)
+}
+
+func v19CreateTemplateShareTable(db *sql.Tx) {
+	sqlStatement := `
+	CREATE TABLE "bot::templateShare" (
+		"code" TEXT PRIMARY KEY,
+		"tgChatId" NUMERIC REFERENCES "auth::user" ("tgChatId") NOT NULL,
+		"name" TEXT NOT NULL,
+		"template" TEXT NOT NULL,
+		"created" TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	`
+	_, err := db.Exec(sqlStatement)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
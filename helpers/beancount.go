@@ -0,0 +1,42 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Account names as defined by the beancount grammar: at least two components separated by colons.
+// Each component starts with a capital letter (or a digit for all but the root component),
+// followed by letters, digits or dashes.
+var (
+	accountRootPattern      = regexp.MustCompile(`^\p{Lu}[\p{L}\p{Nd}-]*$`)
+	accountComponentPattern = regexp.MustCompile(`^[\p{Lu}\p{Nd}][\p{L}\p{Nd}-]*$`)
+)
+
+func IsValidAccount(account string) error {
+	components := strings.Split(account, ":")
+	if len(components) < 2 {
+		return fmt.Errorf("the account '%s' needs to consist of at least two components separated by ':', e.g. 'Expenses:Food'", account)
+	}
+	if !accountRootPattern.MatchString(components[0]) {
+		return fmt.Errorf("the account '%s' needs to start with a capitalized root component, e.g. 'Assets' or 'Expenses'", account)
+	}
+	for _, component := range components[1:] {
+		if !accountComponentPattern.MatchString(component) {
+			return fmt.Errorf("the account component '%s' of '%s' needs to start with a capital letter or a digit and may only contain letters, digits and dashes", component, account)
+		}
+	}
+	return nil
+}
+
+// Commodity (currency) symbols as defined by the beancount grammar: up to 24 capital letters, digits or one of "'._-".
+// They start with a capital letter and end with a capital letter or digit.
+var commodityPattern = regexp.MustCompile(`^[A-Z]([A-Z0-9'._-]{0,22}[A-Z0-9])?$`)
+
+func IsValidCommodity(commodity string) error {
+	if !commodityPattern.MatchString(commodity) {
+		return fmt.Errorf("the commodity '%s' needs to start with a capital letter, end with a capital letter or digit and may only contain capital letters, digits and the characters \"'._-\" in between, e.g. 'EUR' or 'VTI'", commodity)
+	}
+	return nil
+}
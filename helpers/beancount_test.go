@@ -0,0 +1,49 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func TestIsValidAccount(t *testing.T) {
+	for _, valid := range []string{
+		"Assets:Cash",
+		"Expenses:Food:Coffee",
+		"Liabilities:CreditCard-1",
+		"Assets:2022:Savings",
+		"Expenses:Käse",
+	} {
+		if err := helpers.IsValidAccount(valid); err != nil {
+			t.Errorf("Account '%s' should be valid: %s", valid, err.Error())
+		}
+	}
+	for _, invalid := range []string{
+		"",
+		"Assets",
+		"expenses:Food",
+		"Expenses:food",
+		"Expenses::Food",
+		"Expenses:Food:",
+		"Expenses:Food Court",
+		"Expenses:Food_Court",
+		"1Assets:Cash",
+	} {
+		if err := helpers.IsValidAccount(invalid); err == nil {
+			t.Errorf("Account '%s' should be invalid", invalid)
+		}
+	}
+}
+
+func TestIsValidCommodity(t *testing.T) {
+	for _, valid := range []string{"EUR", "VTI", "X", "BRK.B", "NT.TO", "HOOL_2", "A1"} {
+		if err := helpers.IsValidCommodity(valid); err != nil {
+			t.Errorf("Commodity '%s' should be valid: %s", valid, err.Error())
+		}
+	}
+	for _, invalid := range []string{"", "eur", "1EUR", "EUR.", "E UR", "€", "ABCDEFGHIJKLMNOPQRSTUVWXY"} {
+		if err := helpers.IsValidCommodity(invalid); err == nil {
+			t.Errorf("Commodity '%s' should be invalid", invalid)
+		}
+	}
+}
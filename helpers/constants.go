@@ -5,9 +5,16 @@ import "strings"
 const (
 	FIELD_DATE        = "date"
 	FIELD_DESCRIPTION = "description"
+	FIELD_PAYEE       = "payee"
 	FIELD_AMOUNT      = "amount"
 	FIELD_ACCOUNT     = "account"
 	FIELD_TAG         = "tag"
+	FIELD_PRICE       = "price"
+	FIELD_FLAG        = "flag"
+	FIELD_META        = "meta"
+	FIELD_POSTING     = "posting"
+	FIELD_SPLIT       = "split"
+	FIELD_CURRENCY    = "currency"
 
 	FIELD_ACCOUNT_FROM = "from"
 	FIELD_ACCOUNT_TO   = "to"
@@ -21,6 +28,8 @@ const (
 	USERSET_TAG          = "user.vacationTag"
 	USERSET_TZOFF        = "user.tzOffset"
 	USERSET_OMITCMDSLASH = "user.omitCommandSlash"
+	USERSET_LOCALE       = "user.locale"
+	USERSET_COMMODITIES  = "user.commodities"
 
 	DEFAULT_CURRENCY = "EUR"
 
@@ -30,7 +39,9 @@ const (
 func AllowedSuggestionTypes() []string {
 	return []string{
 		FIELD_DESCRIPTION,
+		FIELD_PAYEE,
 		FIELD_ACCOUNT,
+		FIELD_META,
 	}
 }
 
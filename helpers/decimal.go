@@ -0,0 +1,159 @@
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// Decimal is an exact decimal number. Operations never modify their operands,
+// but always return a new Decimal.
+type Decimal struct {
+	r *big.Rat
+}
+
+const DEFAULT_CURRENCY_PRECISION = 2
+
+// CURRENCY_PRECISION holds the decimal places of currencies deviating from DEFAULT_CURRENCY_PRECISION
+var CURRENCY_PRECISION = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"ISK": 0,
+	"CLP": 0,
+	"VND": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"BTC": 8,
+}
+
+func CurrencyPrecision(currency string) int {
+	if precision, exists := CURRENCY_PRECISION[currency]; exists {
+		return precision
+	}
+	return DEFAULT_CURRENCY_PRECISION
+}
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return Decimal{}, fmt.Errorf("'%s' is not a decimal number", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("'%s' is not a decimal number", s)
+	}
+	return Decimal{r}, nil
+}
+
+func NewDecimal(i int64) Decimal {
+	return Decimal{new(big.Rat).SetInt64(i)}
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	return Decimal{new(big.Rat).Add(d.rat(), o.rat())}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	return Decimal{new(big.Rat).Sub(d.rat(), o.rat())}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{new(big.Rat).Mul(d.rat(), o.rat())}
+}
+
+// Quo divides d by o. o must not be zero.
+func (d Decimal) Quo(o Decimal) Decimal {
+	return Decimal{new(big.Rat).Quo(d.rat(), o.rat())}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{new(big.Rat).Neg(d.rat())}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{new(big.Rat).Abs(d.rat())}
+}
+
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	return d.rat().Cmp(o.rat())
+}
+
+// Round rounds half away from zero to the given number of decimal places
+func (d Decimal) Round(places int) Decimal {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	num := new(big.Int).Mul(d.rat().Num(), scale)
+	den := d.rat().Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{new(big.Rat).SetFrac(q, scale)}
+}
+
+// Places returns the number of decimal places needed to represent d exactly.
+// If d has no finite decimal representation (e.g. 1/3), exact is false.
+func (d Decimal) Places() (places int, exact bool) {
+	den := new(big.Int).Set(d.rat().Denom())
+	twos, fives := 0, 0
+	m := new(big.Int)
+	for {
+		q, r := new(big.Int).QuoRem(den, big.NewInt(2), m)
+		if r.Sign() != 0 {
+			break
+		}
+		den = q
+		twos++
+	}
+	for {
+		q, r := new(big.Int).QuoRem(den, big.NewInt(5), m)
+		if r.Sign() != 0 {
+			break
+		}
+		den = q
+		fives++
+	}
+	places = twos
+	if fives > places {
+		places = fives
+	}
+	return places, den.Cmp(big.NewInt(1)) == 0
+}
+
+// Format prints d with at least minPlaces decimal places. Values without finite
+// decimal representation are rounded to minPlaces.
+func (d Decimal) Format(minPlaces int) string {
+	places, exact := d.Places()
+	if !exact {
+		return d.Round(minPlaces).Format(minPlaces)
+	}
+	if places < minPlaces {
+		places = minPlaces
+	}
+	return d.rat().FloatString(places)
+}
+
+func (d Decimal) String() string {
+	return d.Format(0)
+}
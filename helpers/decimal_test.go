@@ -0,0 +1,64 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func decimal(t *testing.T, s string) helpers.Decimal {
+	d, err := helpers.ParseDecimal(s)
+	if err != nil {
+		t.Errorf("Unexpected error parsing decimal: %s", err.Error())
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	helpers.TestExpect(t, decimal(t, "17.3456").String(), "17.3456", "")
+	helpers.TestExpect(t, decimal(t, "-0.5").String(), "-0.5", "")
+	helpers.TestExpect(t, decimal(t, ".5").String(), "0.5", "")
+	helpers.TestExpect(t, decimal(t, "006").String(), "6", "")
+
+	for _, invalid := range []string{"", "abc", "1/3", "1e3", "1.2.3", "1,5"} {
+		if _, err := helpers.ParseDecimal(invalid); err == nil {
+			t.Errorf("Expected error parsing '%s'", invalid)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is not exactly representable as float64
+	helpers.TestExpect(t, decimal(t, "0.1").Add(decimal(t, "0.2")).String(), "0.3", "exact addition")
+	helpers.TestExpect(t, decimal(t, "1").Sub(decimal(t, "0.01")).String(), "0.99", "")
+	helpers.TestExpect(t, decimal(t, "1.1").Mul(decimal(t, "3.5")).String(), "3.85", "")
+	helpers.TestExpect(t, decimal(t, "10").Quo(helpers.NewDecimal(4)).String(), "2.5", "")
+	helpers.TestExpect(t, decimal(t, "-3").Abs().String(), "3", "")
+	helpers.TestExpect(t, decimal(t, "3").Neg().Sign(), -1, "")
+	helpers.TestExpect(t, decimal(t, "3").Cmp(decimal(t, "3.00")), 0, "")
+}
+
+func TestDecimalRoundAndFormat(t *testing.T) {
+	third := helpers.NewDecimal(10).Quo(helpers.NewDecimal(3))
+	_, exact := third.Places()
+	helpers.TestExpect(t, exact, false, "10/3 has no finite decimal representation")
+	helpers.TestExpect(t, third.Format(2), "3.33", "")
+	helpers.TestExpect(t, third.Round(0).String(), "3", "")
+
+	helpers.TestExpect(t, decimal(t, "5.255").Round(2).String(), "5.26", "round half away from zero")
+	helpers.TestExpect(t, decimal(t, "-5.255").Round(2).String(), "-5.26", "round half away from zero (negative)")
+	helpers.TestExpect(t, decimal(t, "5.254").Round(2).String(), "5.25", "")
+
+	helpers.TestExpect(t, decimal(t, "17").Format(2), "17.00", "pad to minimum places")
+	helpers.TestExpect(t, decimal(t, "17.3456").Format(2), "17.3456", "keep higher precision")
+	helpers.TestExpect(t, decimal(t, "1000.00").Format(0), "1000", "")
+	places, _ := decimal(t, "1.125").Places()
+	helpers.TestExpect(t, places, 3, "")
+}
+
+func TestCurrencyPrecision(t *testing.T) {
+	helpers.TestExpect(t, helpers.CurrencyPrecision("EUR"), 2, "")
+	helpers.TestExpect(t, helpers.CurrencyPrecision("JPY"), 0, "")
+	helpers.TestExpect(t, helpers.CurrencyPrecision("BTC"), 8, "")
+	helpers.TestExpect(t, helpers.CurrencyPrecision(""), 2, "")
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Locale determines the separators of amounts entered by and shown to the user.
+// Amounts written to beancount always use '.' as decimal separator and no grouping.
+type Locale struct {
+	Name              string
+	Description       string
+	DecimalSeparator  string
+	GroupingSeparator string
+}
+
+// LOCALE_AUTO guesses the separators from each input, e.g. '1.234,56' or '1,234.56'
+const LOCALE_AUTO = "auto"
+
+var LOCALES = []*Locale{
+	{Name: "en", Description: "1,234.56", DecimalSeparator: ".", GroupingSeparator: ","},
+	{Name: "de", Description: "1.234,56", DecimalSeparator: ",", GroupingSeparator: "."},
+	{Name: "ch", Description: "1'234.56", DecimalSeparator: ".", GroupingSeparator: "'"},
+}
+
+// GetLocale returns the locale with the given name, or nil for LOCALE_AUTO
+func GetLocale(name string) (*Locale, error) {
+	if name == "" || name == LOCALE_AUTO {
+		return nil, nil
+	}
+	for _, l := range LOCALES {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown locale '%s'", name)
+}
+
+func (l *Locale) String() string {
+	if l == nil {
+		return LOCALE_AUTO
+	}
+	return l.Name
+}
+
+// IsNumberRune checks whether the rune may be part of a number in this locale
+func (l *Locale) IsNumberRune(r rune) bool {
+	if r >= '0' && r <= '9' {
+		return true
+	}
+	if l == nil {
+		return r == '.' || r == ','
+	}
+	return strings.ContainsRune(l.DecimalSeparator+l.GroupingSeparator, r)
+}
+
+// Normalize converts a number entered in this locale into its canonical form, e.g. '1.234,5' -> '1234.5' for 'de'
+func (l *Locale) Normalize(value string) (string, error) {
+	err := fmt.Errorf("invalid separators in value '%s' for locale '%s' (e.g. %s)", value, l.Name, l.Description)
+	parts := strings.SplitN(value, l.DecimalSeparator, 2)
+	integer, hasFraction := parts[0], len(parts) == 2
+	fraction := ""
+	if hasFraction {
+		fraction = parts[1]
+	}
+	if hasFraction && (strings.Contains(fraction, l.DecimalSeparator) || strings.Contains(fraction, l.GroupingSeparator)) {
+		return "", err
+	}
+	const DIGITS_PER_BLOCK = 3
+	blocks := strings.Split(integer, l.GroupingSeparator)
+	for idx, block := range blocks {
+		if (idx != 0 && len(block) != DIGITS_PER_BLOCK) || (len(blocks) > 1 && block == "") {
+			return "", err
+		}
+	}
+	normalized := strings.Join(blocks, "")
+	if hasFraction {
+		normalized += "." + fraction
+	}
+	return normalized, nil
+}
+
+var canonicalNumberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Format shows the canonical numbers contained in s in this locale, e.g. '1234.50 EUR' -> '1.234,50 EUR' for 'de'
+func (l *Locale) Format(s string) string {
+	if l == nil {
+		return s
+	}
+	return canonicalNumberPattern.ReplaceAllStringFunc(s, func(number string) string {
+		parts := strings.SplitN(number, ".", 2)
+		integer := parts[0]
+		grouped := ""
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				grouped += l.GroupingSeparator
+			}
+			grouped += string(digit)
+		}
+		if len(parts) == 2 {
+			grouped += l.DecimalSeparator + parts[1]
+		}
+		return grouped
+	})
+}
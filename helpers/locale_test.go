@@ -0,0 +1,53 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/LucaBernstein/beancount-bot-tg/helpers"
+)
+
+func TestLocaleNormalize(t *testing.T) {
+	de, err := helpers.GetLocale("de")
+	if err != nil {
+		t.Fatalf("Getting locale should work: %s", err.Error())
+	}
+	for input, expected := range map[string]string{
+		"1.234,56":  "1234.56",
+		"1234,5":    "1234.5",
+		"1.234":     "1234",
+		"1.234.567": "1234567",
+		"12":        "12",
+	} {
+		normalized, err := de.Normalize(input)
+		if err != nil {
+			t.Errorf("Normalizing '%s' should work: %s", input, err.Error())
+		}
+		helpers.TestExpect(t, normalized, expected, input)
+	}
+	for _, invalid := range []string{"1.5", "1,2,3", "1,234.5", ".123", "1.23.456"} {
+		if _, err := de.Normalize(invalid); err == nil {
+			t.Errorf("Normalizing '%s' should fail", invalid)
+		}
+	}
+
+	en, _ := helpers.GetLocale("en")
+	normalized, _ := en.Normalize("1,234")
+	helpers.TestExpect(t, normalized, "1234", "grouping separator should not be taken for the decimal separator")
+
+	auto, err := helpers.GetLocale(helpers.LOCALE_AUTO)
+	helpers.TestExpect(t, auto == nil && err == nil, true, "")
+	_, err = helpers.GetLocale("xx")
+	if err == nil {
+		t.Errorf("Unknown locale should be rejected")
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	de, _ := helpers.GetLocale("de")
+	ch, _ := helpers.GetLocale("ch")
+	var auto *helpers.Locale
+	helpers.TestExpect(t, de.Format("1234.50 EUR"), "1.234,50 EUR", "")
+	helpers.TestExpect(t, de.Format("12.50 USD @ 0.92 EUR"), "12,50 USD @ 0,92 EUR", "")
+	helpers.TestExpect(t, ch.Format("1234567.5"), "1'234'567.5", "")
+	helpers.TestExpect(t, auto.Format("1234.50 EUR"), "1234.50 EUR", "amounts should be left as they are without locale")
+}
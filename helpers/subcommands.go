@@ -81,8 +81,15 @@ func ExtractTypeValue(params ...string) (*TV, error) {
 }
 
 func SplitQuotedCommand(s string) (res []string) {
+	res, _ = SplitQuotedCommandTokens(s)
+	return
+}
+
+// SplitQuotedCommandTokens splits like SplitQuotedCommand and additionally tells for each token whether it has been (partly) quoted
+func SplitQuotedCommandTokens(s string) (res []string, isQuotedToken []bool) {
 	isEscaped := false
 	isQuoted := false
+	hasQuotes := false
 	split := ""
 	for _, c := range s {
 		if isEscaped {
@@ -96,23 +103,28 @@ func SplitQuotedCommand(s string) (res []string) {
 		}
 		if c == '"' || c == '“' {
 			isQuoted = !isQuoted
+			hasQuotes = true
 			continue
 		}
 		if c == ' ' && !isQuoted {
 			if split == "" {
+				hasQuotes = false
 				continue
 			}
 			res = append(res, split)
+			isQuotedToken = append(isQuotedToken, hasQuotes)
 			split = ""
+			hasQuotes = false
 			continue
 		}
 		split += string(c)
 	}
 	if split != "" {
 		res = append(res, split)
+		isQuotedToken = append(isQuotedToken, hasQuotes)
 	}
 	if isEscaped || isQuoted {
-		return []string{}
+		return []string{}, []bool{}
 	}
 	return
 }
@@ -1,6 +1,7 @@
 package helpers_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/LucaBernstein/beancount-bot-tg/helpers"
@@ -69,6 +70,12 @@ func TestSplitQuotedCommand(t *testing.T) {
 	helpers.TestExpectArrEq(t, helpers.SplitQuotedCommand(`"onlyquoted"`), []string{"onlyquoted"}, "")
 }
 
+func TestSplitQuotedCommandTokens(t *testing.T) {
+	tokens, isQuoted := helpers.SplitQuotedCommandTokens(`lunch 12 "Last Monday" hello" world" "" friday`)
+	helpers.TestExpectArrEq(t, tokens, []string{"lunch", "12", "Last Monday", "hello world", "friday"}, "")
+	helpers.TestExpect(t, fmt.Sprint(isQuoted), "[false false true true false]", "quoted tokens")
+}
+
 func TestSubcommandAddingWarnings(t *testing.T) {
 	sh := helpers.MakeSubcommandHandler("base", true)
 	result := 0
@@ -4,7 +4,7 @@ Feature: Bot suggestions keyboard
     Given I have a bot
     When I send the message "/suggestions rm account:from"
       And I wait 0.2 seconds
-      And I send the message "/suggestions add account:from fromAccount"
+      And I send the message "/suggestions add account:from Assets:FromAccount"
       And I wait 0.3 seconds
     When I send the message "1.00"
       And I wait 0.2 seconds
@@ -12,7 +12,7 @@ Feature: Bot suggestions keyboard
       And the response should include the message "Automatically created a new transaction for you"
     When I send the message "unimportant_description"
     Then 1 messages should be sent back
-      And the response should have a keyboard with the first entry being "fromAccount"
+      And the response should have a keyboard with the first entry being "Assets:FromAccount"
     When I send the message "/cancel"
 
   Scenario: Last used suggestion appears on top
@@ -21,17 +21,17 @@ Feature: Bot suggestions keyboard
       And I wait 0.2 seconds
       And I send the message "/suggestions rm account:from"
       And I wait 0.2 seconds
-      And I send the message "/suggestions add account:from fromAccount"
+      And I send the message "/suggestions add account:from Assets:FromAccount"
       And I wait 0.2 seconds
-      And I create a simple tx with amount 1.23 and desc Test Tx and account:from someFromAccount and account:to someToAccount
+      And I create a simple tx with amount 1.23 and desc Test Tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.2 seconds
       And I send the message "/list"
     Then 1 messages should be sent back
-      And the response should include the message "  someFromAccount                              -1.23 EUR"
+      And the response should include the message "  Assets:SomeFromAccount                       -1.23 EUR"
     When I send the message "1.00"
     Then 2 messages should be sent back
       And the response should include the message "Automatically created a new transaction for you"
     When I send the message "unimportant_description"
     Then 1 messages should be sent back
-      And the response should have a keyboard with the first entry being "someFromAccount"
+      And the response should have a keyboard with the first entry being "Assets:SomeFromAccount"
     When I send the message "/cancel"
@@ -8,7 +8,7 @@ Feature: List transactions
       And I wait 0.1 seconds
     Then 1 messages should be sent back
       And the response should include the message "You might also be looking for archived transactions using '/list archived'."
-    When I create a simple tx with amount 1.23 and desc Test Tx and account:from someFromAccount and account:to someToAccount
+    When I create a simple tx with amount 1.23 and desc Test Tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.2 seconds
     When I send the message "/list"
       And I wait 0.2 seconds
@@ -25,7 +25,7 @@ Feature: List transactions
     Given I have a bot
     When I send the message "/deleteAll yes"
       And I wait 0.2 seconds
-      And I create a simple tx with amount 1.23 and desc Test Tx and account:from someFromAccount and account:to someToAccount
+      And I create a simple tx with amount 1.23 and desc Test Tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.3 seconds
     When I send the message "/list dated"
       And I wait 0.2 seconds
@@ -40,7 +40,7 @@ Feature: List transactions
       And I wait 0.2 seconds
     Then 1 messages should be sent back
       And the response should include the message "You might also be looking for transactions using '/list'."
-    When I create a simple tx with amount 1.23 and desc Test Tx and account:from someFromAccount and account:to someToAccount
+    When I create a simple tx with amount 1.23 and desc Test Tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.2 seconds
     When I send the message "/list archived"
       And I wait 0.2 seconds
@@ -57,9 +57,9 @@ Feature: List transactions
     Given I have a bot
     When I send the message "/deleteAll yes"
       And I wait 0.2 seconds
-      And I create a simple tx with amount 1.23 and desc Test Tx and account:from someFromAccount and account:to someToAccount
+      And I create a simple tx with amount 1.23 and desc Test Tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.3 seconds
-      And I create a simple tx with amount 1.23 and desc Another tx and account:from someFromAccount and account:to someToAccount
+      And I create a simple tx with amount 1.23 and desc Another tx and account:from Assets:SomeFromAccount and account:to Expenses:SomeToAccount
       And I wait 0.3 seconds
     When I send the message "/list numbered"
       And I wait 0.2 seconds
@@ -24,12 +24,42 @@ Feature: Transactions
     When I send the message "any random tx description"
     Then 1 messages should be sent back
       And the response should include the message "enter the **account** the money came **from**"
-    When I send the message "FromAccount"
+    When I send the message "Assets:FromAccount"
     Then 1 messages should be sent back
       And the response should include the message "enter the **account** the money went **to**"
-    When I send the message "ToAccount"
+    When I send the message "Expenses:ToAccount"
+    Then 1 messages should be sent back
+      And the response should include the message "like to **add another posting**"
+    When I send the message "Done"
     Then 1 messages should be sent back
       And the response should include the message "Successfully recorded your transaction."
     When I send the message "/list"
     Then 1 messages should be sent back
       And the response should include the message "any random tx description"
+
+  Scenario: Split a transaction onto several postings
+    Given I have a bot
+    When I send the message "/deleteAll yes"
+      And I wait 0.2 seconds
+      And I send the message "30"
+      And I wait 0.1 seconds
+      And I send the message "Dinner"
+      And I wait 0.1 seconds
+      And I send the message "Assets:FromAccount"
+      And I wait 0.1 seconds
+      And I send the message "Expenses:Food"
+      And I wait 0.1 seconds
+      And I send the message "Add another posting"
+    Then 1 messages should be sent back
+      And the response should include the message "enter the **account** of the **additional posting**"
+    When I send the message "Expenses:Drinks"
+      And I wait 0.1 seconds
+      And I send the message "10"
+    Then 1 messages should be sent back
+      And the response should include the message "remaining amount of 20.00 EUR"
+    When I send the message "Done"
+    Then 1 messages should be sent back
+      And the response should include the message "Successfully recorded your transaction."
+    When I send the message "/list"
+    Then 1 messages should be sent back
+      And the response should include the message "  Expenses:Drinks                              10.00 EUR"
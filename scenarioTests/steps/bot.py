@@ -158,6 +158,6 @@ async def step_impl(context, shouldShouldNot):
 @when('I create a simple tx with amount {amount} and desc {desc} and account:from {account_from} and account:to {account_to}')
 @async_run_until_complete
 async def step_impl(context, amount, desc, account_from, account_to):
-    for command in ["/cancel", amount, desc, account_from, account_to]:
+    for command in ["/cancel", amount, desc, account_from, account_to, "Done"]:
         context.offsetId = (await bot_send_message(context.chat, context.testChatId, command)).id
         await wait_seconds(0.1)